@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// config is read from a JSON file such as
+//
+//	{"api_key": "...", "endpoint": "https://api.coinjar.io/v1"}
+//
+// COINJAR_API_KEY and COINJAR_ENDPOINT override the file.
+type config struct {
+	APIKey   string `json:"api_key"`
+	Endpoint string `json:"endpoint"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "coinjar", "config.json")
+}
+
+// loadConfig reads path, or the default config file if path is empty. Only a
+// missing default file is not an error.
+func loadConfig(path string, getenv func(string) string) (cfg config, err error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("config %s: %v", path, err)
+			}
+		case explicit || !os.IsNotExist(err):
+			return cfg, err
+		}
+	}
+	if key := getenv("COINJAR_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if endpoint := getenv("COINJAR_ENDPOINT"); endpoint != "" {
+		cfg.Endpoint = endpoint
+	}
+	return cfg, nil
+}
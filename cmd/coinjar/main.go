@@ -0,0 +1,276 @@
+// Command coinjar queries the CoinJar API from the command line.
+//
+//	coinjar [-format table|json|csv] [-config file] [-endpoint url] <command> [args]
+//
+// Commands:
+//
+//	account
+//	addresses list [-limit n] [-offset n] [-all]
+//	addresses get <address>
+//	contacts list [-limit n] [-offset n] [-all]
+//	contacts get <uuid>
+//	payments list [-limit n] [-offset n] [-all]
+//	payments get <uuid>
+//	transactions list [-limit n] [-offset n] [-all]
+//	transactions get <uuid>
+//	rate <currency>
+//
+// The API key is read from the COINJAR_API_KEY environment variable, or from
+// the api_key field of the JSON config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+var errUsage = errors.New("usage")
+
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("coinjar", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "table", "output format: table, json or csv")
+	configPath := flags.String("config", "", "config file (default "+defaultConfigPath()+")")
+	endpoint := flags.String("endpoint", "", "API endpoint (default "+coinjar.DefaultEndpoint+")")
+	flags.Usage = func() { usage(stderr, flags) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "coinjar: unknown format %q\n", *format)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, getenv)
+	if err != nil {
+		fail(stderr, err)
+		return 1
+	}
+	if *endpoint != "" {
+		cfg.Endpoint = *endpoint
+	}
+	if cfg.APIKey == "" {
+		fmt.Fprintln(stderr, "coinjar: no API key, set COINJAR_API_KEY or api_key in the config file")
+		return 1
+	}
+
+	opts := []coinjar.Option{coinjar.WithUserAgent("coinjar-cli")}
+	if cfg.Endpoint != "" {
+		opts = append(opts, coinjar.WithEndpoint(cfg.Endpoint))
+	}
+	client := coinjar.New(cfg.APIKey, opts...)
+
+	out, err := dispatch(context.Background(), client, flags.Args(), stderr)
+	if err == errUsage {
+		flags.Usage()
+		return 2
+	}
+	if err == nil {
+		err = render(stdout, out)
+	}
+	if err != nil {
+		fail(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// fail reports err, which often already carries the package's "coinjar:"
+// prefix.
+func fail(w io.Writer, err error) {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "coinjar: ") {
+		msg = "coinjar: " + msg
+	}
+	fmt.Fprintln(w, msg)
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprint(w, `Usage: coinjar [flags] <command> [args]
+
+Commands:
+  account
+  addresses list|get <address>
+  contacts list|get <uuid>
+  payments list|get <uuid>
+  transactions list|get <uuid>
+  rate <currency>
+
+Flags:
+`)
+	flags.PrintDefaults()
+}
+
+func dispatch(ctx context.Context, client *coinjar.Client, args []string, stderr io.Writer) (*output, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "account":
+		if len(rest) != 0 {
+			return nil, errUsage
+		}
+		user, err := client.AccountContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return accountOutput(user), nil
+
+	case "rate":
+		if len(rest) != 1 {
+			return nil, errUsage
+		}
+		currency := strings.ToUpper(rest[0])
+		rate, err := client.FairRateContext(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		return rateOutput(currency, rate), nil
+
+	case "addresses", "contacts", "payments", "transactions":
+		if len(rest) == 0 {
+			return nil, errUsage
+		}
+		switch rest[0] {
+		case "list":
+			page, err := parseListFlags(cmd, rest[1:], stderr)
+			if err != nil {
+				return nil, err
+			}
+			return list(ctx, client, cmd, page)
+		case "get":
+			if len(rest) != 2 {
+				return nil, errUsage
+			}
+			return get(ctx, client, cmd, rest[1])
+		}
+	}
+	return nil, errUsage
+}
+
+// listPage is nil when every record should be fetched.
+type listPage struct {
+	limit, offset int
+}
+
+func parseListFlags(cmd string, args []string, stderr io.Writer) (*listPage, error) {
+	flags := flag.NewFlagSet(cmd+" list", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	limit := flags.Int("limit", 100, "number of records")
+	offset := flags.Int("offset", 0, "records to skip")
+	all := flags.Bool("all", false, "fetch every record")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return nil, errUsage
+	}
+	if *all {
+		return nil, nil
+	}
+	return &listPage{*limit, *offset}, nil
+}
+
+func list(ctx context.Context, client *coinjar.Client, cmd string, page *listPage) (*output, error) {
+	switch cmd {
+	case "addresses":
+		var addresses []coinjar.BitcoinAddress
+		var err error
+		if page == nil {
+			addresses, err = client.AllBitcoinAddresses(ctx, nil)
+		} else {
+			addresses, err = client.ListBitcoinAddressesContext(ctx, page.limit, page.offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return addressesOutput(addresses), nil
+	case "contacts":
+		var contacts []coinjar.Contact
+		var err error
+		if page == nil {
+			contacts, err = client.AllContacts(ctx, nil)
+		} else {
+			contacts, err = client.ListContactsContext(ctx, page.limit, page.offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return contactsOutput(contacts), nil
+	case "payments":
+		var payments []coinjar.Payment
+		var err error
+		if page == nil {
+			payments, err = client.AllPayments(ctx, nil)
+		} else {
+			payments, err = client.ListPaymentsContext(ctx, page.limit, page.offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return paymentsOutput(payments), nil
+	default:
+		var transactions []coinjar.Transaction
+		var err error
+		if page == nil {
+			transactions, err = client.AllTransactions(ctx, nil)
+		} else {
+			transactions, err = client.ListTransactionsContext(ctx, page.limit, page.offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return transactionsOutput(transactions), nil
+	}
+}
+
+func get(ctx context.Context, client *coinjar.Client, cmd, id string) (*output, error) {
+	switch cmd {
+	case "addresses":
+		address, err := client.BitcoinAddressContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			return nil, coinjar.ErrNotFound
+		}
+		return addressesOutput([]coinjar.BitcoinAddress{*address}).single(), nil
+	case "contacts":
+		contact, err := client.ContactContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if contact == nil {
+			return nil, coinjar.ErrNotFound
+		}
+		return contactsOutput([]coinjar.Contact{*contact}).single(), nil
+	case "payments":
+		payment, err := client.PaymentContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if payment == nil {
+			return nil, coinjar.ErrNotFound
+		}
+		return paymentsOutput([]coinjar.Payment{*payment}).single(), nil
+	default:
+		transaction, err := client.TransactionContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if transaction == nil {
+			return nil, coinjar.ErrNotFound
+		}
+		return transactionsOutput([]coinjar.Transaction{*transaction}).single(), nil
+	}
+}
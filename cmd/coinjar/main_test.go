@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+func newFake() *coinjartest.Server {
+	fake := coinjartest.NewServer("someapikey")
+	fake.SetAccount(coinjar.User{
+		UUID:             "29d7f276-ba50-11e3-b016-7eddf9792095",
+		Email:            "test@example.com",
+		FullName:         "John Doe",
+		AvailableBalance: coinjar.MustParseAmount("1.0"),
+	})
+	fake.AddContact(coinjar.Contact{
+		UUID:      "e359fd02-0079-4ef0-9f1f-706a84f39cca",
+		Name:      "Ryan Zhou",
+		PayeeName: "ryan@coinjar.io",
+		PayeeType: coinjar.PayeeTypeWallet,
+	})
+	for i := 0; i < 3; i++ {
+		fake.AddTransaction(coinjar.Transaction{
+			Amount:           coinjar.MustParseAmount("0.5"),
+			Status:           coinjar.TransactionReceived,
+			CounterpartyName: "Customer, Inc",
+		})
+	}
+	fake.SetFairRate("USD", coinjar.FairRate{
+		Bid:  coinjar.MustParseAmount("101.4713"),
+		Ask:  coinjar.MustParseAmount("103.5213"),
+		Spot: coinjar.MustParseAmount("102.4963"),
+	})
+	return fake
+}
+
+func runCLI(fake *coinjartest.Server, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	env := map[string]string{"COINJAR_API_KEY": "someapikey", "COINJAR_ENDPOINT": fake.URL}
+	code = run(args, &out, &errOut, func(k string) string { return env[k] })
+	return code, out.String(), errOut.String()
+}
+
+func TestAccountTable(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+
+	code, stdout, _ := runCLI(fake, "-config", writeConfig(t, `{}`), "account")
+	assertEqual(t, code, 0)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assertEqual(t, len(lines), 2)
+	assertEqual(t, strings.Fields(lines[0])[0], "UUID")
+	assertEqual(t, strings.Fields(lines[1])[1], "test@example.com")
+}
+
+func TestRateJSON(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+
+	code, stdout, _ := runCLI(fake, "-config", writeConfig(t, `{}`), "-format", "json", "rate", "usd")
+	assertEqual(t, code, 0)
+	var rate coinjar.FairRate
+	assertEqual(t, json.Unmarshal([]byte(stdout), &rate), nil)
+	assertEqual(t, rate.Spot.String(), "102.4963")
+}
+
+func TestTransactionsCSV(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+
+	code, stdout, _ := runCLI(fake, "-config", writeConfig(t, `{}`), "-format", "csv", "transactions", "list", "-limit", "2")
+	assertEqual(t, code, 0)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assertEqual(t, len(lines), 3)
+	assertEqual(t, lines[0], "uuid,status,amount,confirmations,counterparty_name,reference,created_at")
+	assertEqual(t, strings.Contains(lines[1], `,RECEIVED,0.5,0,"Customer, Inc",,`), true)
+
+	code, stdout, _ = runCLI(fake, "-config", writeConfig(t, `{}`), "-format", "csv", "transactions", "list", "-all")
+	assertEqual(t, code, 0)
+	assertEqual(t, strings.Count(stdout, "\n"), 4)
+}
+
+func TestGetJSON(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+
+	code, stdout, _ := runCLI(fake, "-config", writeConfig(t, `{}`), "-format", "json", "contacts", "get", "e359fd02-0079-4ef0-9f1f-706a84f39cca")
+	assertEqual(t, code, 0)
+	var contact coinjar.Contact
+	assertEqual(t, json.Unmarshal([]byte(stdout), &contact), nil)
+	assertEqual(t, contact.Name, "Ryan Zhou")
+
+	code, _, stderr := runCLI(fake, "-config", writeConfig(t, `{}`), "payments", "get", "missing")
+	assertEqual(t, code, 1)
+	assertEqual(t, stderr, "coinjar: GET payments/missing.json: 404 Not Found\n")
+}
+
+func TestGetNull(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"transaction": null}`))
+	}))
+	defer ts.Close()
+
+	var out, errOut bytes.Buffer
+	env := map[string]string{"COINJAR_API_KEY": "someapikey", "COINJAR_ENDPOINT": ts.URL}
+	code := run([]string{"-config", writeConfig(t, `{}`), "transactions", "get", "1"}, &out, &errOut, func(k string) string { return env[k] })
+	assertEqual(t, code, 1)
+	assertEqual(t, errOut.String(), "coinjar: not found\n")
+}
+
+func TestConfigFile(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+
+	path := writeConfig(t, `{"api_key": "someapikey", "endpoint": "`+fake.URL+`"}`)
+	var out, errOut bytes.Buffer
+	code := run([]string{"-config", path, "account"}, &out, &errOut, func(string) string { return "" })
+	assertEqual(t, code, 0)
+	assertEqual(t, strings.Contains(out.String(), "test@example.com"), true)
+
+	code = run([]string{"-config", path, "account"}, &out, &errOut, func(k string) string {
+		if k == "COINJAR_API_KEY" {
+			return "wrongkey"
+		}
+		return ""
+	})
+	assertEqual(t, code, 1)
+}
+
+func TestUsageErrors(t *testing.T) {
+	fake := newFake()
+	defer fake.Close()
+	config := writeConfig(t, `{}`)
+
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"contacts"},
+		{"contacts", "get"},
+		{"rate"},
+		{"transactions", "list", "extra"},
+	} {
+		code, _, _ := runCLI(fake, append([]string{"-config", config}, args...)...)
+		assertEqual(t, code, 2)
+	}
+
+	code, _, stderr := runCLI(fake, "-config", config, "-format", "xml", "account")
+	assertEqual(t, code, 2)
+	assertEqual(t, stderr, "coinjar: unknown format \"xml\"\n")
+
+	var out, errOut bytes.Buffer
+	code = run([]string{"-config", config, "account"}, &out, &errOut, func(string) string { return "" })
+	assertEqual(t, code, 1)
+}
+
+// configDir holds the files written by writeConfig, removed by TestMain.
+var configDir string
+
+func TestMain(m *testing.M) {
+	var err error
+	if configDir, err = ioutil.TempDir("", "coinjar"); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(configDir)
+	os.Exit(code)
+}
+
+func writeConfig(t *testing.T, content string) string {
+	f, err := ioutil.TempFile(configDir, "config*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// output is the result of a command: rows for table and CSV output, and the
+// API value itself for JSON output.
+type output struct {
+	header []string
+	rows   [][]string
+	value  interface{}
+}
+
+// single makes JSON output the only element of a one-element list.
+func (o *output) single() *output {
+	o.value = reflect.ValueOf(o.value).Index(0).Interface()
+	return o
+}
+
+var renderers = map[string]func(io.Writer, *output) error{
+	"table": renderTable,
+	"json":  renderJSON,
+	"csv":   renderCSV,
+}
+
+func renderTable(w io.Writer, o *output) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	io.WriteString(tw, strings.ToUpper(strings.Join(o.header, "\t"))+"\n")
+	for _, row := range o.rows {
+		io.WriteString(tw, strings.Join(row, "\t")+"\n")
+	}
+	return tw.Flush()
+}
+
+func renderJSON(w io.Writer, o *output) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(o.value)
+}
+
+func renderCSV(w io.Writer, o *output) error {
+	cw := csv.NewWriter(w)
+	cw.Write(o.header)
+	cw.WriteAll(o.rows)
+	return cw.Error()
+}
+
+func accountOutput(u *coinjar.User) *output {
+	return &output{
+		header: []string{"uuid", "email", "full_name", "available_balance", "unconfirmed_balance"},
+		rows: [][]string{{
+			u.UUID, u.Email, u.FullName, u.AvailableBalance.String(), u.UnconfirmedBalance.String(),
+		}},
+		value: u,
+	}
+}
+
+func rateOutput(currency string, r *coinjar.FairRate) *output {
+	return &output{
+		header: []string{"currency", "bid", "ask", "spot"},
+		rows:   [][]string{{currency, r.Bid.String(), r.Ask.String(), r.Spot.String()}},
+		value:  r,
+	}
+}
+
+func addressesOutput(addresses []coinjar.BitcoinAddress) *output {
+	o := &output{
+		header: []string{"address", "label", "total_confirmed", "total_received"},
+		value:  addresses,
+	}
+	for _, a := range addresses {
+		o.rows = append(o.rows, []string{a.Address, a.Label, a.TotalConfirmed.String(), a.TotalReceived.String()})
+	}
+	return o
+}
+
+func contactsOutput(contacts []coinjar.Contact) *output {
+	o := &output{
+		header: []string{"uuid", "name", "payee_name", "payee_type", "created_at"},
+		value:  contacts,
+	}
+	for _, c := range contacts {
+		o.rows = append(o.rows, []string{c.UUID, c.Name, c.PayeeName, c.PayeeType, c.CreatedAt.String()})
+	}
+	return o
+}
+
+func paymentsOutput(payments []coinjar.Payment) *output {
+	o := &output{
+		header: []string{"uuid", "status", "amount", "payee_name", "payee_type", "reference", "created_at"},
+		value:  payments,
+	}
+	for _, p := range payments {
+		o.rows = append(o.rows, []string{
+			p.UUID, string(p.Status), p.Amount.String(), p.PayeeName, p.PayeeType, p.Reference, p.CreatedAt.String(),
+		})
+	}
+	return o
+}
+
+func transactionsOutput(transactions []coinjar.Transaction) *output {
+	o := &output{
+		header: []string{"uuid", "status", "amount", "confirmations", "counterparty_name", "reference", "created_at"},
+		value:  transactions,
+	}
+	for _, t := range transactions {
+		o.rows = append(o.rows, []string{
+			t.UUID, string(t.Status), t.Amount.String(), strconv.Itoa(t.Confirmations), t.CounterpartyName, t.Reference, t.CreatedAt.String(),
+		})
+	}
+	return o
+}
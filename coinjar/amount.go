@@ -0,0 +1,319 @@
+package coinjar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// AmountDecimals is the number of decimal places an Amount holds: satoshi
+// precision for bitcoin, which also covers fiat minor units and rates.
+const AmountDecimals = 8
+
+const amountScale = 100000000
+
+// Amount is an exact decimal value with AmountDecimals places. The API sends
+// amounts as decimal strings, and String formats them the same way. An
+// Amount parsed from text keeps that text, returned by Raw and MarshalJSON.
+//
+// Amounts range over about ±92 billion, plus or minus 92,233,720,368.54775807.
+// Arithmetic that would leave that range panics rather than wrapping around.
+type Amount struct {
+	units int64
+	raw   string
+}
+
+func ParseAmount(s string) (Amount, error) {
+	text := strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	whole, frac := text, ""
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		whole, frac = text[:i], text[i+1:]
+	}
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Amount{}, fmt.Errorf("coinjar: invalid amount %q", s)
+	}
+	if len(frac) > AmountDecimals {
+		return Amount{}, fmt.Errorf("coinjar: amount %q has more than %d decimal places", s, AmountDecimals)
+	}
+	digits := whole + frac + strings.Repeat("0", AmountDecimals-len(frac))
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("coinjar: amount %q out of range", s)
+	}
+	if neg {
+		units = -units
+	}
+	return Amount{units: units, raw: s}, nil
+}
+
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func AmountFromSatoshis(satoshis int64) Amount {
+	return Amount{units: satoshis}
+}
+
+// AmountFromMinorUnits converts an integer count of minor units, such as
+// cents with places 2, to an Amount.
+// It panics if the result is out of range.
+func AmountFromMinorUnits(units int64, places int) Amount {
+	return mulDiv(units, pow10(AmountDecimals-checkPlaces(places)), 1)
+}
+
+func (a Amount) Satoshis() int64 {
+	return a.units
+}
+
+// MinorUnits returns a rounded to places and expressed as an integer count of
+// those units, e.g. cents for places 2.
+func (a Amount) MinorUnits(places int) int64 {
+	return a.Round(places).units / pow10(AmountDecimals-places)
+}
+
+// Raw returns the text a was parsed from, or "" if a was computed.
+func (a Amount) Raw() string {
+	return a.raw
+}
+
+const errAmountOverflow = "coinjar: amount out of range"
+
+// Add returns a+b. It panics if the sum is out of range.
+func (a Amount) Add(b Amount) Amount {
+	sum := a.units + b.units
+	if (sum > a.units) != (b.units > 0) {
+		panic(errAmountOverflow)
+	}
+	return Amount{units: sum}
+}
+
+// Sub returns a-b. It panics if the difference is out of range.
+func (a Amount) Sub(b Amount) Amount {
+	diff := a.units - b.units
+	if (diff < a.units) != (b.units > 0) {
+		panic(errAmountOverflow)
+	}
+	return Amount{units: diff}
+}
+
+func (a Amount) Neg() Amount {
+	if a.units == math.MinInt64 {
+		panic(errAmountOverflow)
+	}
+	return Amount{units: -a.units}
+}
+
+func (a Amount) IsZero() bool { return a.units == 0 }
+
+func (a Amount) Abs() Amount {
+	if a.units < 0 {
+		return a.Neg()
+	}
+	return a
+}
+
+func (a Amount) Sign() int {
+	switch {
+	case a.units < 0:
+		return -1
+	case a.units > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or
+// greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	}
+	return 0
+}
+
+// Equal reports whether a and b have the same value, whatever text they were
+// parsed from.
+func (a Amount) Equal(b Amount) bool {
+	return a.units == b.units
+}
+
+// Mul returns a*b rounded half away from zero to AmountDecimals places. It
+// panics if the product is out of range.
+func (a Amount) Mul(b Amount) Amount {
+	return mulDiv(a.units, b.units, amountScale)
+}
+
+// Quo returns a/b rounded half away from zero to AmountDecimals places. It
+// panics if b is zero or the quotient is out of range.
+func (a Amount) Quo(b Amount) Amount {
+	if b.units == 0 {
+		panic("coinjar: division of amount by zero")
+	}
+	return mulDiv(a.units, amountScale, b.units)
+}
+
+func mulDiv(x, y, d int64) Amount {
+	n := new(big.Int).Mul(big.NewInt(x), big.NewInt(y))
+	den := big.NewInt(d)
+	q, r := new(big.Int).QuoRem(n, den, new(big.Int))
+	// QuoRem truncates towards zero, so round the remainder half away from zero.
+	if r.Sign() != 0 {
+		twice := new(big.Int).Lsh(new(big.Int).Abs(r), 1)
+		if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+			if (n.Sign() < 0) != (d < 0) {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	if !q.IsInt64() {
+		panic(errAmountOverflow)
+	}
+	return Amount{units: q.Int64()}
+}
+
+// Round returns a rounded half away from zero to the given number of decimal
+// places.
+func (a Amount) Round(places int) Amount {
+	unit := pow10(AmountDecimals - checkPlaces(places))
+	rem := a.units % unit
+	units := a.units - rem
+	if abs64(rem)*2 >= unit {
+		if a.units < 0 {
+			return Amount{units: units}.Sub(Amount{units: unit})
+		}
+		return Amount{units: units}.Add(Amount{units: unit})
+	}
+	return Amount{units: units}
+}
+
+// String formats a without trailing zeros, keeping at least one decimal
+// place: "1.0", "-0.01", "21.71364123".
+func (a Amount) String() string {
+	s := a.StringFixed(AmountDecimals)
+	s = strings.TrimRight(s, "0")
+	if strings.HasSuffix(s, ".") {
+		s += "0"
+	}
+	return s
+}
+
+// StringFixed formats a rounded to exactly places decimal places.
+func (a Amount) StringFixed(places int) string {
+	r := a.Round(places)
+	u := uint64(r.units)
+	sign := ""
+	if r.units < 0 {
+		u = uint64(-r.units)
+		sign = "-"
+	}
+	whole := strconv.FormatUint(u/amountScale, 10)
+	if places == 0 {
+		return sign + whole
+	}
+	frac := fmt.Sprintf("%08d", u%amountScale)
+	return sign + whole + "." + frac[:places]
+}
+
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = Amount{}
+		return nil
+	}
+	parsed, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON writes the text a was parsed from if there is any, so amounts
+// decoded from the API are written back as they were sent.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.raw != "" {
+		return []byte(strconv.Quote(a.raw)), nil
+	}
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+// UnmarshalJSON accepts quoted decimals, bare numbers and null, which decodes
+// to zero.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		*a = Amount{}
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	return a.UnmarshalText([]byte(text))
+}
+
+func checkPlaces(places int) int {
+	if places < 0 || places > AmountDecimals {
+		panic(fmt.Sprintf("coinjar: decimal places %d out of range", places))
+	}
+	return places
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+var errZeroRate = errors.New("coinjar: fair rate spot price is not positive")
+
+// ToFiat converts a bitcoin amount to the rate's currency at the spot price.
+func (r *FairRate) ToFiat(btc Amount) Amount {
+	return btc.Mul(r.Spot)
+}
+
+// ToBTC converts an amount in the rate's currency to bitcoin at the spot
+// price, rounded to the nearest satoshi.
+func (r *FairRate) ToBTC(fiat Amount) (Amount, error) {
+	if r.Spot.Sign() <= 0 {
+		return Amount{}, errZeroRate
+	}
+	return fiat.Quo(r.Spot), nil
+}
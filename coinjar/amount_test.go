@@ -0,0 +1,150 @@
+package coinjar
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	for _, c := range []struct {
+		in       string
+		satoshis int64
+		out      string
+	}{
+		{"1.0", 100000000, "1.0"},
+		{"0.3", 30000000, "0.3"},
+		{"21.71364123", 2171364123, "21.71364123"},
+		{"-1.25", -125000000, "-1.25"},
+		{"-0.01", -1000000, "-0.01"},
+		{"+7", 700000000, "7.0"},
+		{".5", 50000000, "0.5"},
+		{"102.4963", 10249630000, "102.4963"},
+		{"1.50", 150000000, "1.5"},
+		{"0", 0, "0.0"},
+	} {
+		a, err := ParseAmount(c.in)
+		assertNil(t, err)
+		assertEqual(t, a.Satoshis(), c.satoshis)
+		assertEqual(t, a.String(), c.out)
+	}
+
+	for _, in := range []string{"", "-", ".", "1.2.3", "abc", "1e5", "1.123456789", "99999999999999999999"} {
+		_, err := ParseAmount(in)
+		assertNotNil(t, err)
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	a := MustParseAmount("1.25")
+	b := MustParseAmount("0.3")
+
+	assertEqual(t, a.Add(b).String(), "1.55")
+	assertEqual(t, a.Sub(b).String(), "0.95")
+	assertEqual(t, b.Sub(a).String(), "-0.95")
+	assertEqual(t, a.Neg().Abs().Equal(a), true)
+	assertEqual(t, a.Mul(b).String(), "0.375")
+	assertEqual(t, MustParseAmount("1").Quo(MustParseAmount("3")).String(), "0.33333333")
+	assertEqual(t, MustParseAmount("2").Quo(MustParseAmount("3")).String(), "0.66666667")
+	assertEqual(t, MustParseAmount("-2").Quo(MustParseAmount("3")).String(), "-0.66666667")
+	assertEqual(t, MustParseAmount("0.1").Add(MustParseAmount("0.2")).Equal(MustParseAmount("0.3")), true)
+
+	assertEqual(t, a.Cmp(b), 1)
+	assertEqual(t, b.Cmp(a), -1)
+	assertEqual(t, a.Cmp(MustParseAmount("1.250")), 0)
+	assertEqual(t, Amount{}.IsZero(), true)
+	assertEqual(t, a.Neg().Sign(), -1)
+	assertEqual(t, a.Equal(MustParseAmount("1.250")), true)
+}
+
+func TestAmountRaw(t *testing.T) {
+	a := MustParseAmount("5")
+	assertEqual(t, a.Raw(), "5")
+	assertEqual(t, a.String(), "5.0")
+	assertEqual(t, a.Add(Amount{}).Raw(), "")
+
+	var decoded struct{ Amount Amount }
+	assertNil(t, json.Unmarshal([]byte(`{"Amount": "1.50"}`), &decoded))
+	assertEqual(t, decoded.Amount.Raw(), "1.50")
+	out, err := json.Marshal(decoded)
+	assertNil(t, err)
+	assertEqual(t, string(out), `{"Amount":"1.50"}`)
+	out, err = json.Marshal(decoded.Amount.Add(AmountFromSatoshis(1)))
+	assertNil(t, err)
+	assertEqual(t, string(out), `"1.50000001"`)
+}
+
+func TestAmountOverflow(t *testing.T) {
+	max := AmountFromSatoshis(math.MaxInt64)
+	min := AmountFromSatoshis(math.MinInt64)
+	for name, fn := range map[string]func(){
+		"add":         func() { max.Add(AmountFromSatoshis(1)) },
+		"sub":         func() { min.Sub(AmountFromSatoshis(1)) },
+		"neg":         func() { min.Neg() },
+		"mul":         func() { max.Mul(MustParseAmount("2")) },
+		"quo":         func() { max.Quo(MustParseAmount("0.5")) },
+		"quo by zero": func() { max.Quo(Amount{}) },
+		"minor units": func() { AmountFromMinorUnits(math.MaxInt64, 2) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+	assertEqual(t, max.Sub(AmountFromSatoshis(1)).Add(AmountFromSatoshis(1)).Equal(max), true)
+	assertEqual(t, min.Add(AmountFromSatoshis(1)).Sub(AmountFromSatoshis(1)).Equal(min), true)
+}
+
+func TestAmountRounding(t *testing.T) {
+	a := MustParseAmount("12.345")
+	assertEqual(t, a.StringFixed(2), "12.35")
+	assertEqual(t, a.Neg().StringFixed(2), "-12.35")
+	assertEqual(t, a.StringFixed(0), "12")
+	assertEqual(t, a.StringFixed(4), "12.3450")
+	assertEqual(t, a.MinorUnits(2), int64(1235))
+	assertEqual(t, AmountFromMinorUnits(1235, 2).String(), "12.35")
+	assertEqual(t, AmountFromSatoshis(1).String(), "0.00000001")
+}
+
+func TestAmountJSON(t *testing.T) {
+	var v struct {
+		A Amount
+		B Amount
+		C Amount
+		D Amount
+	}
+	err := json.Unmarshal([]byte(`{"a": "1.5", "b": 2.25, "c": null, "d": ""}`), &v)
+	assertNil(t, err)
+	assertEqual(t, v.A.String(), "1.5")
+	assertEqual(t, v.B.String(), "2.25")
+	assertEqual(t, v.C.IsZero(), true)
+	assertEqual(t, v.D.IsZero(), true)
+
+	out, err := json.Marshal(v)
+	assertNil(t, err)
+	assertEqual(t, string(out), `{"A":"1.5","B":"2.25","C":"0.0","D":"0.0"}`)
+
+	err = json.Unmarshal([]byte(`{"a": "lots"}`), &v)
+	assertNotNil(t, err)
+}
+
+func TestFairRateConversion(t *testing.T) {
+	rate := &FairRate{
+		Bid:  MustParseAmount("101.4713"),
+		Ask:  MustParseAmount("103.5213"),
+		Spot: MustParseAmount("102.4963"),
+	}
+	assertEqual(t, rate.ToFiat(MustParseAmount("0.5")).String(), "51.24815")
+	assertEqual(t, rate.ToFiat(MustParseAmount("0.5")).StringFixed(2), "51.25")
+
+	btc, err := rate.ToBTC(MustParseAmount("100.00"))
+	assertNil(t, err)
+	assertEqual(t, btc.String(), "0.97564497")
+
+	_, err = (&FairRate{}).ToBTC(MustParseAmount("100.00"))
+	assertNotNil(t, err)
+}
@@ -0,0 +1,335 @@
+package coinjar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidURI is wrapped by the errors ParsePaymentURI and
+// PaymentURI.Validate return.
+var ErrInvalidURI = errors.New("coinjar: invalid bitcoin URI")
+
+// PaymentURI is a BIP21 "bitcoin:" URI asking for a payment to Address. A
+// zero Amount is left out of the URI, and so are empty Label and Message.
+//
+//	uri := address.PaymentURI(coinjar.MustParseAmount("0.25"), "Order 42")
+//	uri.String() // "bitcoin:1BoatSLRHtKNngkdXEeobR76b53LETtpyT?amount=0.25&label=Donations&message=Order%2042"
+type PaymentURI struct {
+	Address string
+	Amount  Amount
+	Label   string
+	Message string
+	// Params holds any other parameters, unescaped.
+	Params map[string]string
+}
+
+// PaymentURI returns a URI asking for amount to be paid to a, labelled with
+// a's label.
+func (a BitcoinAddress) PaymentURI(amount Amount, message string) PaymentURI {
+	return PaymentURI{Address: a.Address, Amount: amount, Label: a.Label, Message: message}
+}
+
+// FiatPaymentURI is PaymentURI for an amount in rate's currency, converted to
+// bitcoin at the spot price.
+func (a BitcoinAddress) FiatPaymentURI(rate *FairRate, fiat Amount, message string) (PaymentURI, error) {
+	amount, err := rate.ToBTC(fiat)
+	if err != nil {
+		return PaymentURI{}, err
+	}
+	return a.PaymentURI(amount, message), nil
+}
+
+// FiatAmount converts the requested amount to rate's currency.
+func (u PaymentURI) FiatAmount(rate *FairRate) Amount {
+	return rate.ToFiat(u.Amount)
+}
+
+// String formats u with every parameter value percent-encoded. Parameters in
+// Params follow amount, label and message in key order.
+func (u PaymentURI) String() string {
+	var b strings.Builder
+	b.WriteString("bitcoin:")
+	b.WriteString(u.Address)
+	sep := byte('?')
+	param := func(key, value string) {
+		b.WriteByte(sep)
+		b.WriteString(uriEscape(key))
+		b.WriteByte('=')
+		b.WriteString(uriEscape(value))
+		sep = '&'
+	}
+	if !u.Amount.IsZero() {
+		param("amount", u.Amount.String())
+	}
+	if u.Label != "" {
+		param("label", u.Label)
+	}
+	if u.Message != "" {
+		param("message", u.Message)
+	}
+	keys := make([]string, 0, len(u.Params))
+	for key := range u.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		param(key, u.Params[key])
+	}
+	return b.String()
+}
+
+// Validate checks that u has a well-formed address, a non-negative amount and
+// no parameter in Params that duplicates amount, label or message.
+func (u PaymentURI) Validate() error {
+	if !validAddress(u.Address) {
+		return fmt.Errorf("%w: malformed address %q", ErrInvalidURI, u.Address)
+	}
+	if u.Amount.Sign() < 0 {
+		return fmt.Errorf("%w: negative amount %s", ErrInvalidURI, u.Amount)
+	}
+	for key := range u.Params {
+		switch key {
+		case "":
+			return fmt.Errorf("%w: empty parameter name", ErrInvalidURI)
+		case "amount", "label", "message":
+			return fmt.Errorf("%w: %s set in Params", ErrInvalidURI, key)
+		}
+	}
+	return nil
+}
+
+// ParsePaymentURI parses a BIP21 URI. The scheme is matched without regard to
+// case. It rejects URIs with a malformed address, an amount that is not a
+// plain decimal of at most 8 places, a malformed percent-encoding, a repeated
+// parameter, or a "req-" parameter, which BIP21 requires clients that do not
+// understand it to refuse.
+func ParsePaymentURI(s string) (PaymentURI, error) {
+	const scheme = "bitcoin:"
+	if len(s) < len(scheme) || !strings.EqualFold(s[:len(scheme)], scheme) {
+		return PaymentURI{}, fmt.Errorf("%w: scheme is not %q", ErrInvalidURI, scheme)
+	}
+	rest := s[len(scheme):]
+	address, query := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		address, query = rest[:i], rest[i+1:]
+	}
+
+	u := PaymentURI{Address: address}
+	seen := make(map[string]bool)
+	var pairs []string
+	if query != "" {
+		pairs = strings.Split(query, "&")
+	}
+	for _, pair := range pairs {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return PaymentURI{}, fmt.Errorf("%w: malformed parameter %q", ErrInvalidURI, pair)
+		}
+		key, err := url.PathUnescape(pair[:i])
+		if err != nil {
+			return PaymentURI{}, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+		}
+		value, err := url.PathUnescape(pair[i+1:])
+		if err != nil {
+			return PaymentURI{}, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+		}
+		if seen[key] {
+			return PaymentURI{}, fmt.Errorf("%w: repeated parameter %q", ErrInvalidURI, key)
+		}
+		seen[key] = true
+
+		switch {
+		case key == "amount":
+			if u.Amount, err = parseURIAmount(value); err != nil {
+				return PaymentURI{}, err
+			}
+		case key == "label":
+			u.Label = value
+		case key == "message":
+			u.Message = value
+		case strings.HasPrefix(key, "req-"):
+			return PaymentURI{}, fmt.Errorf("%w: unsupported required parameter %q", ErrInvalidURI, key)
+		default:
+			if u.Params == nil {
+				u.Params = make(map[string]string)
+			}
+			u.Params[key] = value
+		}
+	}
+	if err := u.Validate(); err != nil {
+		return PaymentURI{}, err
+	}
+	return u, nil
+}
+
+// parseURIAmount accepts only what BIP21 allows: digits with an optional
+// decimal point, in bitcoin.
+func parseURIAmount(s string) (Amount, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Amount{}, fmt.Errorf("%w: malformed amount %q", ErrInvalidURI, s)
+	}
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	return amount, nil
+}
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Alphabet = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// validAddress reports whether s is a base58check address or a bech32 or
+// bech32m segwit address, checksum included, for mainnet, testnet or regtest.
+func validAddress(s string) bool {
+	lower := strings.ToLower(s)
+	for _, hrp := range []string{"bc", "tb", "bcrt"} {
+		if strings.HasPrefix(lower, hrp+"1") {
+			return validSegwitAddress(s, hrp)
+		}
+	}
+	return validBase58Address(s)
+}
+
+// Version bytes of mainnet and testnet pay-to-pubkey-hash and
+// pay-to-script-hash addresses.
+var base58Versions = []byte{0x00, 0x05, 0x6f, 0xc4}
+
+func validBase58Address(s string) bool {
+	if len(s) < 26 || len(s) > 35 || !containsOnly(s, base58Alphabet) {
+		return false
+	}
+	n := new(big.Int)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	for _, c := range s {
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(strings.IndexRune(base58Alphabet, c))))
+	}
+	// Each leading '1' stands for a leading zero byte.
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	decoded := append(make([]byte, zeros), n.Bytes()...)
+	if len(decoded) != 25 || bytes.IndexByte(base58Versions, decoded[0]) < 0 {
+		return false
+	}
+	first := sha256.Sum256(decoded[:21])
+	second := sha256.Sum256(first[:])
+	return bytes.Equal(second[:4], decoded[21:])
+}
+
+// Checksum constants of BIP 173 bech32, used by segwit version 0, and BIP 350
+// bech32m, used by later versions.
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func validSegwitAddress(s, hrp string) bool {
+	lower := strings.ToLower(s)
+	// Bech32 addresses may be all upper case, but not mixed.
+	if s != lower && s != strings.ToUpper(s) {
+		return false
+	}
+	data := lower[len(hrp)+1:]
+	if len(s) > 90 || len(data) < 7 || !containsOnly(data, bech32Alphabet) {
+		return false
+	}
+	values := make([]byte, len(data))
+	for i := range data {
+		values[i] = byte(strings.IndexByte(bech32Alphabet, data[i]))
+	}
+	version := values[0]
+	want := uint32(bech32mConst)
+	if version == 0 {
+		want = bech32Const
+	}
+	if version > 16 || bech32Polymod(hrp, values) != want {
+		return false
+	}
+	program, ok := convertBits(values[1:len(values)-6], 5, 8)
+	if !ok || len(program) < 2 || len(program) > 40 {
+		return false
+	}
+	return version != 0 || len(program) == 20 || len(program) == 32
+}
+
+func bech32Polymod(hrp string, values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	step := func(v byte) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range generator {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] >> 5)
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] & 31)
+	}
+	for _, v := range values {
+		step(v)
+	}
+	return chk
+}
+
+// convertBits regroups data from groups of from bits to groups of to bits,
+// rejecting non-zero or overlong padding.
+func convertBits(data []byte, from, to uint) ([]byte, bool) {
+	var acc, bits uint
+	var out []byte
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&(1<<to-1)))
+		}
+	}
+	if bits >= from || acc<<(to-bits)&(1<<to-1) != 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+func containsOnly(s, alphabet string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune(alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// uriEscape percent-encodes everything but RFC 3986 unreserved characters,
+// so spaces become %20 rather than +.
+func uriEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package coinjar
+
+import (
+	"errors"
+	"testing"
+)
+
+const testAddress = "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+
+func TestPaymentURIString(t *testing.T) {
+	address := BitcoinAddress{Address: testAddress, Label: "Luke-Jr's shop"}
+	uri := address.PaymentURI(MustParseAmount("20.3"), "Order #42 & more")
+	assertEqual(t, uri.String(), "bitcoin:"+testAddress+"?amount=20.3&label=Luke-Jr%27s%20shop&message=Order%20%2342%20%26%20more")
+	assertNil(t, uri.Validate())
+
+	bare := BitcoinAddress{Address: testAddress}.PaymentURI(Amount{}, "")
+	assertEqual(t, bare.String(), "bitcoin:"+testAddress)
+
+	uri.Params = map[string]string{"somethingyoudontunderstand": "50", "r": "https://example.com/pay?id=1"}
+	assertEqual(t, uri.String(), "bitcoin:"+testAddress+"?amount=20.3&label=Luke-Jr%27s%20shop&message=Order%20%2342%20%26%20more"+
+		"&r=https%3A%2F%2Fexample.com%2Fpay%3Fid%3D1&somethingyoudontunderstand=50")
+
+	rate := &FairRate{Spot: MustParseAmount("102.4963")}
+	fiat, err := address.FiatPaymentURI(rate, MustParseAmount("100.00"), "")
+	assertNil(t, err)
+	assertEqual(t, fiat.Amount.String(), "0.97564497")
+	assertEqual(t, fiat.FiatAmount(rate).StringFixed(2), "100.00")
+	_, err = address.FiatPaymentURI(&FairRate{}, MustParseAmount("100.00"), "")
+	assertNotNil(t, err)
+}
+
+func TestParsePaymentURI(t *testing.T) {
+	uri, err := ParsePaymentURI("BITCOIN:" + testAddress + "?amount=50&label=Luke-Jr&message=Donation%20for%20project%20xyz+1&foo=bar")
+	assertNil(t, err)
+	assertEqual(t, uri.Address, testAddress)
+	assertEqual(t, uri.Amount, MustParseAmount("50"))
+	assertEqual(t, uri.Label, "Luke-Jr")
+	assertEqual(t, uri.Message, "Donation for project xyz+1")
+	assertEqual(t, uri.Params["foo"], "bar")
+
+	for _, s := range []string{
+		"bitcoin:bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq",
+		"bitcoin:BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ?amount=.5",
+		"bitcoin:bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+		"bitcoin:3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"bitcoin:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		"bitcoin:" + testAddress + "?",
+		"bitcoin:" + testAddress + "?amount=0.00000001",
+	} {
+		_, err := ParsePaymentURI(s)
+		assertNil(t, err)
+	}
+
+	// Round trip.
+	orig := BitcoinAddress{Address: testAddress, Label: "café ☕"}.PaymentURI(MustParseAmount("0.001"), "100% = a+b?")
+	parsed, err := ParsePaymentURI(orig.String())
+	assertNil(t, err)
+	assertEqual(t, parsed.String(), orig.String())
+	assertEqual(t, parsed.Label, "café ☕")
+	assertEqual(t, parsed.Message, "100% = a+b?")
+
+	for _, s := range []string{
+		"",
+		"litecoin:" + testAddress,
+		"bitcoin:",
+		"bitcoin:1BoatSLRHtKNngkdXEeobR76b53LETtpy0",
+		"bitcoin:bc1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ",
+		// One character mistyped, which the checksum catches.
+		"bitcoin:1BoatSLRHtKNngkdXEeobR76b53LETtpyU",
+		"bitcoin:3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLz",
+		"bitcoin:bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdx",
+		"bitcoin:bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj1",
+		"bitcoin:" + testAddress + "?amount=1,5",
+		"bitcoin:" + testAddress + "?amount=-1",
+		"bitcoin:" + testAddress + "?amount=1e3",
+		"bitcoin:" + testAddress + "?amount=0.123456789",
+		"bitcoin:" + testAddress + "?amount=1&amount=2",
+		"bitcoin:" + testAddress + "?label=%zz",
+		"bitcoin:" + testAddress + "?label",
+		"bitcoin:" + testAddress + "?amount=1&&label=x",
+		"bitcoin:" + testAddress + "?req-somethingyoudontunderstand=50",
+	} {
+		_, err := ParsePaymentURI(s)
+		assertEqual(t, errors.Is(err, ErrInvalidURI), true)
+	}
+}
@@ -1,44 +1,57 @@
 package coinjar
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Client struct {
-	apiKey     string
-	endpoint   string
-	httpClient *http.Client
+	apiKey      string
+	endpoint    string
+	userAgent   string
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	logger      Logger
+	limiter     RateLimiter
 }
 
 func NewClient(apiKey string) *Client {
-	return NewCustomClient(apiKey, "https://api.coinjar.io/v1")
+	return New(apiKey)
 }
 
-func NewCustomClient(apiKey, endpoint string) (c *Client) {
-	c = new(Client)
-	c.apiKey = apiKey
-	c.endpoint = endpoint
-	c.httpClient = new(http.Client)
-	return
+func NewCustomClient(apiKey, endpoint string) *Client {
+	return New(apiKey, WithEndpoint(endpoint))
+}
+
+// SetRetryPolicy makes the client retry failed idempotent requests according
+// to p. A nil policy disables retries, which is the default.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
 }
 
 type User struct {
-	UUID               string
-	Email              string
+	UUID               string `json:"uuid"`
+	Email              string `json:"email"`
 	FullName           string `json:"full_name"`
-	AvailableBalance   string `json:"available_balance"`
-	UnconfirmedBalance string `json:"unconfirmed_balance"`
+	AvailableBalance   Amount `json:"available_balance"`
+	UnconfirmedBalance Amount `json:"unconfirmed_balance"`
 }
 
-func (c *Client) Account() (obj *User, err error) {
-	body, err := c.read("account.json")
+func (c *Client) Account() (*User, error) {
+	return c.AccountContext(context.Background())
+}
+
+func (c *Client) AccountContext(ctx context.Context) (obj *User, err error) {
+	body, err := c.read(ctx, "account.json")
 	if err != nil {
 		return
 	}
@@ -52,18 +65,26 @@ func (c *Client) Account() (obj *User, err error) {
 }
 
 type BitcoinAddress struct {
-	Label          string
-	TotalConfirmed string `json:"total_confirmed"`
-	TotalReceived  string `json:"total_received"`
-	Address        string
+	Label          string `json:"label"`
+	TotalConfirmed Amount `json:"total_confirmed"`
+	TotalReceived  Amount `json:"total_received"`
+	Address        string `json:"address"`
 }
 
 func (c *Client) BitcoinAddresses() ([]BitcoinAddress, error) {
 	return c.ListBitcoinAddresses(100, 0)
 }
 
-func (c *Client) ListBitcoinAddresses(limit, offset int) (obj []BitcoinAddress, err error) {
-	body, err := c.read("bitcoin_addresses.json",
+func (c *Client) BitcoinAddressesContext(ctx context.Context) ([]BitcoinAddress, error) {
+	return c.ListBitcoinAddressesContext(ctx, 100, 0)
+}
+
+func (c *Client) ListBitcoinAddresses(limit, offset int) ([]BitcoinAddress, error) {
+	return c.ListBitcoinAddressesContext(context.Background(), limit, offset)
+}
+
+func (c *Client) ListBitcoinAddressesContext(ctx context.Context, limit, offset int) (obj []BitcoinAddress, err error) {
+	body, err := c.read(ctx, "bitcoin_addresses.json",
 		"limit", strconv.Itoa(limit),
 		"offset", strconv.Itoa(offset))
 	if err != nil {
@@ -80,13 +101,60 @@ func (c *Client) ListBitcoinAddresses(limit, offset int) (obj []BitcoinAddress,
 	return wrapper.Addresses, nil
 }
 
-func (c *Client) BitcoinAddress(address string) (obj *BitcoinAddress, err error) {
-	body, err := c.read("bitcoin_addresses/" + address + ".json")
+func (c *Client) BitcoinAddress(address string) (*BitcoinAddress, error) {
+	return c.BitcoinAddressContext(context.Background(), address)
+}
+
+func (c *Client) BitcoinAddressContext(ctx context.Context, address string) (obj *BitcoinAddress, err error) {
+	body, err := c.read(ctx, "bitcoin_addresses/"+address+".json")
+	if err != nil {
+		return
+	}
+
+	var wrapper struct {
+		Address *BitcoinAddress `json:"bitcoin_address"`
+	}
+	err = json.Unmarshal(body, &wrapper)
+	if err != nil {
+		return
+	}
+	return wrapper.Address, nil
+}
+
+// CreateBitcoinAddress generates a new receiving address with the given label.
+func (c *Client) CreateBitcoinAddress(label string) (*BitcoinAddress, error) {
+	return c.CreateBitcoinAddressContext(context.Background(), label)
+}
+
+func (c *Client) CreateBitcoinAddressContext(ctx context.Context, label string) (obj *BitcoinAddress, err error) {
+	body, err := c.send(ctx, "POST", "bitcoin_addresses.json",
+		"bitcoin_address[label]", label)
+	if err != nil {
+		return
+	}
+
+	var wrapper struct {
+		Address *BitcoinAddress `json:"bitcoin_address"`
+	}
+	err = json.Unmarshal(body, &wrapper)
 	if err != nil {
 		return
 	}
-	if string(body) == "null" {
-		return nil, errors.New("Bitcoin address not found")
+	return wrapper.Address, nil
+}
+
+func (c *Client) UpdateBitcoinAddressLabel(address, label string) (*BitcoinAddress, error) {
+	return c.UpdateBitcoinAddressLabelContext(context.Background(), address, label)
+}
+
+func (c *Client) UpdateBitcoinAddressLabelContext(ctx context.Context, address, label string) (obj *BitcoinAddress, err error) {
+	if address == "" {
+		return nil, errors.New("coinjar: bitcoin address is required")
+	}
+	body, err := c.send(ctx, "PUT", "bitcoin_addresses/"+address+".json",
+		"bitcoin_address[label]", label)
+	if err != nil {
+		return
 	}
 
 	var wrapper struct {
@@ -100,20 +168,28 @@ func (c *Client) BitcoinAddress(address string) (obj *BitcoinAddress, err error)
 }
 
 type Contact struct {
-	UpdatedAt string `json:"updated_at"`
-	UUID      string
-	Name      string
-	PayeeName string `json:"payee_name"`
-	PayeeType string `json:"payee_type"`
-	CreatedAt string `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	UUID      string    `json:"uuid"`
+	Name      string    `json:"name"`
+	PayeeName string    `json:"payee_name"`
+	PayeeType string    `json:"payee_type"`
+	CreatedAt Timestamp `json:"created_at"`
 }
 
 func (c *Client) Contacts() ([]Contact, error) {
 	return c.ListContacts(100, 0)
 }
 
-func (c *Client) ListContacts(limit, offset int) (obj []Contact, err error) {
-	body, err := c.read("contacts.json",
+func (c *Client) ContactsContext(ctx context.Context) ([]Contact, error) {
+	return c.ListContactsContext(ctx, 100, 0)
+}
+
+func (c *Client) ListContacts(limit, offset int) ([]Contact, error) {
+	return c.ListContactsContext(context.Background(), limit, offset)
+}
+
+func (c *Client) ListContactsContext(ctx context.Context, limit, offset int) (obj []Contact, err error) {
+	body, err := c.read(ctx, "contacts.json",
 		"limit", strconv.Itoa(limit),
 		"offset", strconv.Itoa(offset))
 	if err != nil {
@@ -128,14 +204,79 @@ func (c *Client) ListContacts(limit, offset int) (obj []Contact, err error) {
 	return wrapper.Contacts, nil
 }
 
-func (c *Client) Contact(uuid string) (obj *Contact, err error) {
-	body, err := c.read("contacts/" + uuid + ".json")
+func (c *Client) Contact(uuid string) (*Contact, error) {
+	return c.ContactContext(context.Background(), uuid)
+}
+
+func (c *Client) ContactContext(ctx context.Context, uuid string) (obj *Contact, err error) {
+	body, err := c.read(ctx, "contacts/"+uuid+".json")
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Contact *Contact }
+	err = json.Unmarshal(body, &wrapper)
 	if err != nil {
 		return
 	}
+	return wrapper.Contact, nil
+}
+
+const (
+	PayeeTypeWallet         = "WALLET"
+	PayeeTypeBitcoinAddress = "ADDRESS"
+)
 
-	if string(body) == "null" {
-		return nil, errors.New("Contact not found")
+func (contact *Contact) params() ([]string, error) {
+	if contact.Name == "" {
+		return nil, errors.New("coinjar: contact name is required")
+	}
+	if contact.PayeeName == "" {
+		return nil, errors.New("coinjar: contact payee name is required")
+	}
+	switch contact.PayeeType {
+	case PayeeTypeWallet, PayeeTypeBitcoinAddress:
+	default:
+		return nil, fmt.Errorf("coinjar: invalid contact payee type %q", contact.PayeeType)
+	}
+	return []string{
+		"contact[name]", contact.Name,
+		"contact[payee_name]", contact.PayeeName,
+		"contact[payee_type]", contact.PayeeType,
+	}, nil
+}
+
+// CreateContact saves a new contact from the Name, PayeeName and PayeeType
+// of contact.
+func (c *Client) CreateContact(contact Contact) (*Contact, error) {
+	return c.CreateContactContext(context.Background(), contact)
+}
+
+func (c *Client) CreateContactContext(ctx context.Context, contact Contact) (*Contact, error) {
+	return c.saveContact(ctx, "POST", "contacts.json", contact)
+}
+
+// UpdateContact replaces the Name, PayeeName and PayeeType of the contact
+// identified by contact.UUID.
+func (c *Client) UpdateContact(contact Contact) (*Contact, error) {
+	return c.UpdateContactContext(context.Background(), contact)
+}
+
+func (c *Client) UpdateContactContext(ctx context.Context, contact Contact) (*Contact, error) {
+	if contact.UUID == "" {
+		return nil, errors.New("coinjar: contact UUID is required")
+	}
+	return c.saveContact(ctx, "PUT", "contacts/"+contact.UUID+".json", contact)
+}
+
+func (c *Client) saveContact(ctx context.Context, method, api string, contact Contact) (obj *Contact, err error) {
+	params, err := contact.params()
+	if err != nil {
+		return
+	}
+	body, err := c.send(ctx, method, api, params...)
+	if err != nil {
+		return
 	}
 
 	var wrapper struct{ Contact *Contact }
@@ -146,24 +287,67 @@ func (c *Client) Contact(uuid string) (obj *Contact, err error) {
 	return wrapper.Contact, nil
 }
 
+func (c *Client) DeleteContact(uuid string) error {
+	return c.DeleteContactContext(context.Background(), uuid)
+}
+
+func (c *Client) DeleteContactContext(ctx context.Context, uuid string) error {
+	if uuid == "" {
+		return errors.New("coinjar: contact UUID is required")
+	}
+	_, err := c.send(ctx, "DELETE", "contacts/"+uuid+".json")
+	return err
+}
+
+// UpsertContact updates the first contact with the same Name as contact, or
+// creates one if there is none.
+func (c *Client) UpsertContact(contact Contact) (*Contact, error) {
+	return c.UpsertContactContext(context.Background(), contact)
+}
+
+func (c *Client) UpsertContactContext(ctx context.Context, contact Contact) (*Contact, error) {
+	if _, err := contact.params(); err != nil {
+		return nil, err
+	}
+	it := c.IterContacts(ctx, nil)
+	for it.Next() {
+		if existing := it.Value(); existing.Name == contact.Name {
+			contact.UUID = existing.UUID
+			return c.UpdateContactContext(ctx, contact)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return c.CreateContactContext(ctx, contact)
+}
+
 type Payment struct {
-	Status             string
-	Amount             string
-	CreatedAt          string `json:"created_at"`
-	PayeeName          string `json:"payee_name"`
-	PayeeType          string `json:"payee_type"`
-	Reference          string
-	RelatedTransaction *Transaction `json:"related_transaction"`
-	UUID               string
-	UpdatedAt          string `json:"updated_at"`
-}
-
-func (c *Client) Payments() (obj []Payment, err error) {
+	Status             PaymentStatus `json:"status"`
+	Amount             Amount        `json:"amount"`
+	CreatedAt          Timestamp     `json:"created_at"`
+	PayeeName          string        `json:"payee_name"`
+	PayeeType          string        `json:"payee_type"`
+	Reference          string        `json:"reference"`
+	RelatedTransaction *Transaction  `json:"related_transaction"`
+	UUID               string        `json:"uuid"`
+	UpdatedAt          Timestamp     `json:"updated_at"`
+}
+
+func (c *Client) Payments() ([]Payment, error) {
 	return c.ListPayments(100, 0)
 }
 
-func (c *Client) ListPayments(limit, offset int) (obj []Payment, err error) {
-	body, err := c.read("payments.json",
+func (c *Client) PaymentsContext(ctx context.Context) ([]Payment, error) {
+	return c.ListPaymentsContext(ctx, 100, 0)
+}
+
+func (c *Client) ListPayments(limit, offset int) ([]Payment, error) {
+	return c.ListPaymentsContext(context.Background(), limit, offset)
+}
+
+func (c *Client) ListPaymentsContext(ctx context.Context, limit, offset int) (obj []Payment, err error) {
+	body, err := c.read(ctx, "payments.json",
 		"limit", strconv.Itoa(limit),
 		"offset", strconv.Itoa(offset))
 	if err != nil {
@@ -178,13 +362,103 @@ func (c *Client) ListPayments(limit, offset int) (obj []Payment, err error) {
 	return wrapper.Payments, nil
 }
 
-func (c *Client) Payment(uuid string) (obj *Payment, err error) {
-	body, err := c.read("payments/" + uuid + ".json")
+func (c *Client) Payment(uuid string) (*Payment, error) {
+	return c.PaymentContext(context.Background(), uuid)
+}
+
+func (c *Client) PaymentContext(ctx context.Context, uuid string) (obj *Payment, err error) {
+	body, err := c.read(ctx, "payments/"+uuid+".json")
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Payment *Payment }
+	err = json.Unmarshal(body, &wrapper)
+	if err != nil {
+		return
+	}
+	return wrapper.Payment, nil
+}
+
+// PaymentRequest describes an outbound payment. Set exactly one of PayeeName
+// (a bitcoin or email address) and ContactUUID. Currency defaults to BTC.
+type PaymentRequest struct {
+	PayeeName   string
+	ContactUUID string
+	Amount      Amount
+	Currency    string
+	Reference   string
+}
+
+func (r *PaymentRequest) params() ([]string, error) {
+	if (r.PayeeName == "") == (r.ContactUUID == "") {
+		return nil, errors.New("coinjar: payment needs exactly one of PayeeName or ContactUUID")
+	}
+	if r.Amount.Sign() <= 0 {
+		return nil, errors.New("coinjar: payment amount must be positive")
+	}
+	params := []string{"payment[amount]", r.Amount.String()}
+	if r.PayeeName != "" {
+		params = append(params, "payment[payee_name]", r.PayeeName)
+	} else {
+		params = append(params, "payment[contact_uuid]", r.ContactUUID)
+	}
+	if r.Currency != "" {
+		params = append(params, "payment[currency]", r.Currency)
+	}
+	if r.Reference != "" {
+		params = append(params, "payment[reference]", r.Reference)
+	}
+	return params, nil
+}
+
+// CreatePayment creates a pending payment. It is not sent until confirmed
+// with ConfirmPayment.
+func (c *Client) CreatePayment(req PaymentRequest) (*Payment, error) {
+	return c.CreatePaymentContext(context.Background(), req)
+}
+
+func (c *Client) CreatePaymentContext(ctx context.Context, req PaymentRequest) (obj *Payment, err error) {
+	params, err := req.params()
+	if err != nil {
+		return
+	}
+	body, err := c.send(ctx, "POST", "payments.json", params...)
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Payment *Payment }
+	err = json.Unmarshal(body, &wrapper)
 	if err != nil {
 		return
 	}
-	if string(body) == "null" {
-		return nil, errors.New("Payment not found")
+	return wrapper.Payment, nil
+}
+
+func (c *Client) ConfirmPayment(uuid string) (*Payment, error) {
+	return c.ConfirmPaymentContext(context.Background(), uuid)
+}
+
+func (c *Client) ConfirmPaymentContext(ctx context.Context, uuid string) (*Payment, error) {
+	return c.updatePayment(ctx, uuid, "confirm")
+}
+
+func (c *Client) CancelPayment(uuid string) (*Payment, error) {
+	return c.CancelPaymentContext(context.Background(), uuid)
+}
+
+func (c *Client) CancelPaymentContext(ctx context.Context, uuid string) (*Payment, error) {
+	return c.updatePayment(ctx, uuid, "cancel")
+}
+
+func (c *Client) updatePayment(ctx context.Context, uuid, action string) (obj *Payment, err error) {
+	if uuid == "" {
+		return nil, errors.New("coinjar: payment UUID is required")
+	}
+	body, err := c.send(ctx, "PUT", "payments/"+uuid+"/"+action+".json")
+	if err != nil {
+		return
 	}
 
 	var wrapper struct{ Payment *Payment }
@@ -196,30 +470,38 @@ func (c *Client) Payment(uuid string) (obj *Payment, err error) {
 }
 
 type Transaction struct {
-	Amount              string
-	BitcoinTxid         string `json:"bitcoin_txid"`
-	Confirmations       string
-	CounterpartyAddress string `json:"counterparty_address"`
-	CounterpartyName    string `json:"counterparty_name"`
-	CounterpartyType    string `json:"counterparty_type"`
-	CounterpartyUserID  int    `json:"counterparty_user_id"`
-	CreatedAt           string `json:"created_at"`
-	ID                  int
-	PaymentID           int `json:"payment_id"`
-	Reference           string
-	RelatedPaymentUUID  string `json:"related_payment_uuid"`
-	Status              string
-	UUID                string
-	UpdatedAt           string `json:"updated_at"`
-	UserID              int    `json:"user_id"`
+	Amount              Amount            `json:"amount"`
+	BitcoinTxid         string            `json:"bitcoin_txid"`
+	Confirmations       int               `json:"confirmations"`
+	CounterpartyAddress string            `json:"counterparty_address"`
+	CounterpartyName    string            `json:"counterparty_name"`
+	CounterpartyType    string            `json:"counterparty_type"`
+	CounterpartyUserID  int               `json:"counterparty_user_id"`
+	CreatedAt           Timestamp         `json:"created_at"`
+	ID                  int               `json:"id"`
+	PaymentID           int               `json:"payment_id"`
+	Reference           string            `json:"reference"`
+	RelatedPaymentUUID  string            `json:"related_payment_uuid"`
+	Status              TransactionStatus `json:"status"`
+	UUID                string            `json:"uuid"`
+	UpdatedAt           Timestamp         `json:"updated_at"`
+	UserID              int               `json:"user_id"`
 }
 
 func (c *Client) Transactions() ([]Transaction, error) {
 	return c.ListTransactions(100, 0)
 }
 
-func (c *Client) ListTransactions(limit, offset int) (obj []Transaction, err error) {
-	body, err := c.read("transactions.json",
+func (c *Client) TransactionsContext(ctx context.Context) ([]Transaction, error) {
+	return c.ListTransactionsContext(ctx, 100, 0)
+}
+
+func (c *Client) ListTransactions(limit, offset int) ([]Transaction, error) {
+	return c.ListTransactionsContext(context.Background(), limit, offset)
+}
+
+func (c *Client) ListTransactionsContext(ctx context.Context, limit, offset int) (obj []Transaction, err error) {
+	body, err := c.read(ctx, "transactions.json",
 		"limit", strconv.Itoa(limit),
 		"offset", strconv.Itoa(offset))
 	if err != nil {
@@ -234,14 +516,15 @@ func (c *Client) ListTransactions(limit, offset int) (obj []Transaction, err err
 	return wrapper.Transactions, nil
 }
 
-func (c *Client) Transaction(uuid string) (obj *Transaction, err error) {
-	body, err := c.read("transactions/" + uuid + ".json")
+func (c *Client) Transaction(uuid string) (*Transaction, error) {
+	return c.TransactionContext(context.Background(), uuid)
+}
+
+func (c *Client) TransactionContext(ctx context.Context, uuid string) (obj *Transaction, err error) {
+	body, err := c.read(ctx, "transactions/"+uuid+".json")
 	if err != nil {
 		return
 	}
-	if strings.Contains(string(body), "\"status\":\"404\"") {
-		return nil, errors.New(fmt.Sprintf("Transaction not found, response body: %q", body))
-	}
 
 	var wrapper struct{ Transaction *Transaction }
 	err = json.Unmarshal(body, &wrapper)
@@ -252,13 +535,17 @@ func (c *Client) Transaction(uuid string) (obj *Transaction, err error) {
 }
 
 type FairRate struct {
-	Bid  string
-	Ask  string
-	Spot string
+	Bid  Amount `json:"bid"`
+	Ask  Amount `json:"ask"`
+	Spot Amount `json:"spot"`
 }
 
-func (c *Client) FairRate(currency string) (obj *FairRate, err error) {
-	body, err := c.read("fair_rate/" + currency + ".json")
+func (c *Client) FairRate(currency string) (*FairRate, error) {
+	return c.FairRateContext(context.Background(), currency)
+}
+
+func (c *Client) FairRateContext(ctx context.Context, currency string) (obj *FairRate, err error) {
+	body, err := c.read(ctx, "fair_rate/"+currency+".json")
 	if err != nil {
 		return
 	}
@@ -270,13 +557,64 @@ func (c *Client) FairRate(currency string) (obj *FairRate, err error) {
 	return
 }
 
-func (c *Client) read(api string, params ...string) (body []byte, err error) {
-	request, _ := http.NewRequest("GET", c.endpoint+"/"+api, nil)
+func (c *Client) read(ctx context.Context, api string, params ...string) ([]byte, error) {
+	return c.send(ctx, "GET", api, params...)
+}
+
+// send makes a request to the API. params are key/value pairs, sent in the
+// query string for GET requests and as a form body otherwise.
+func (c *Client) send(ctx context.Context, method, api string, params ...string) (body []byte, err error) {
+	var reqBody io.Reader
+	if method != "GET" {
+		reqBody = strings.NewReader(createQuery(params))
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.endpoint+"/"+api, reqBody)
+	if err != nil {
+		return
+	}
 	request.SetBasicAuth(c.apiKey, "")
-	request.URL.RawQuery = createQuery(params)
+	request.Header.Set("User-Agent", c.userAgent)
+	if reqBody != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		request.URL.RawQuery = createQuery(params)
+	}
+
+	for attempt := 1; ; attempt++ {
+		body, err = c.do(ctx, request, api)
+		if err == nil || c.retryPolicy == nil || !isIdempotent(request.Method) {
+			return
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		wait, retry := c.retryPolicy.Retry(attempt, err)
+		if !retry {
+			return
+		}
+		c.logf("coinjar: retrying %s %s in %v after attempt %d: %v", request.Method, api, wait, attempt, err)
+		if err = sleep(ctx, wait); err != nil {
+			return
+		}
+	}
+}
 
+func (c *Client) do(ctx context.Context, request *http.Request, api string) (body []byte, err error) {
+	group := endpointGroup(api)
+	groupLimiter, _ := c.limiter.(GroupRateLimiter)
+	if groupLimiter != nil {
+		err = groupLimiter.WaitGroup(ctx, group)
+	} else if c.limiter != nil {
+		err = c.limiter.Wait(ctx)
+	}
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(request)
 	if err != nil {
+		c.logf("coinjar: %s %s failed after %v: %v", request.Method, api, time.Since(start), err)
 		return
 	}
 
@@ -285,9 +623,23 @@ func (c *Client) read(api string, params ...string) (body []byte, err error) {
 	if err != nil {
 		return
 	}
+	c.logf("coinjar: %s %s %d in %v", request.Method, api, resp.StatusCode, time.Since(start))
+	if resp.StatusCode == http.StatusTooManyRequests && groupLimiter != nil {
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		groupLimiter.Throttled(group, retryAfter)
+	}
+	if err = checkResponse(resp, request.Method, api, body); err != nil {
+		return nil, err
+	}
 	return
 }
 
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
 func createQuery(params []string) string {
 	plen := len(params)
 	if plen%2 == 1 {
@@ -1,13 +1,16 @@
 package coinjar
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAccount(t *testing.T) {
@@ -39,8 +42,8 @@ func TestAccount(t *testing.T) {
 	assertEqual(t, user.UUID, "29d7f276-ba50-11e3-b016-7eddf9792095")
 	assertEqual(t, user.Email, "test@example.com")
 	assertEqual(t, user.FullName, "John Doe")
-	assertEqual(t, user.AvailableBalance, "1.0")
-	assertEqual(t, user.UnconfirmedBalance, "0.3")
+	assertEqual(t, user.AvailableBalance.String(), "1.0")
+	assertEqual(t, user.UnconfirmedBalance.String(), "0.3")
 }
 
 func TestBitcoinAddresses(t *testing.T) {
@@ -81,16 +84,16 @@ func TestBitcoinAddresses(t *testing.T) {
 	{
 		address := addresses[0]
 		assertEqual(t, address.Label, "Mojocoin")
-		assertEqual(t, address.TotalConfirmed, "21.71364123")
-		assertEqual(t, address.TotalReceived, "21.71364124")
+		assertEqual(t, address.TotalConfirmed.String(), "21.71364123")
+		assertEqual(t, address.TotalReceived.String(), "21.71364124")
 		assertEqual(t, address.Address, "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
 	}
 
 	{
 		address := addresses[1]
 		assertEqual(t, address.Label, "")
-		assertEqual(t, address.TotalConfirmed, "0.0")
-		assertEqual(t, address.TotalReceived, "0.0")
+		assertEqual(t, address.TotalConfirmed.String(), "0.0")
+		assertEqual(t, address.TotalReceived.String(), "0.0")
 		assertEqual(t, address.Address, "mg6XEGxLXYVQQxvjVndaPj7hWfoLRz4m84")
 	}
 }
@@ -135,16 +138,16 @@ func TestListBitcoinAddresses(t *testing.T) {
 	{
 		address := addresses[0]
 		assertEqual(t, address.Label, "Mojocoin")
-		assertEqual(t, address.TotalConfirmed, "21.71364123")
-		assertEqual(t, address.TotalReceived, "21.71364124")
+		assertEqual(t, address.TotalConfirmed.String(), "21.71364123")
+		assertEqual(t, address.TotalReceived.String(), "21.71364124")
 		assertEqual(t, address.Address, "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
 	}
 
 	{
 		address := addresses[1]
 		assertEqual(t, address.Label, "")
-		assertEqual(t, address.TotalConfirmed, "0.0")
-		assertEqual(t, address.TotalReceived, "0.0")
+		assertEqual(t, address.TotalConfirmed.String(), "0.0")
+		assertEqual(t, address.TotalReceived.String(), "0.0")
 		assertEqual(t, address.Address, "mg6XEGxLXYVQQxvjVndaPj7hWfoLRz4m84")
 	}
 }
@@ -175,11 +178,77 @@ func TestBitcoinAddress(t *testing.T) {
 	address, err := client.BitcoinAddress("mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
 	assertNil(t, err)
 	assertEqual(t, address.Label, "Mojocoin")
-	assertEqual(t, address.TotalConfirmed, "21.71364123")
-	assertEqual(t, address.TotalReceived, "21.71364124")
+	assertEqual(t, address.TotalConfirmed.String(), "21.71364123")
+	assertEqual(t, address.TotalReceived.String(), "21.71364124")
 	assertEqual(t, address.Address, "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
 }
 
+func TestCreateBitcoinAddress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/bitcoin_addresses.json" {
+			assertEqual(t, r.Method, "POST")
+			assertEqual(t, r.FormValue("bitcoin_address[label]"), "Invoice 42")
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `
+				{
+					"bitcoin_address": {
+						"label": "Invoice 42",
+						"total_confirmed": "0.0",
+						"total_received": "0.0",
+						"address": "mrhz5ZgSF3C1BSdyCKt3gEdhKoRL5BNfJV"
+					}
+				}
+			`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	address, err := client.CreateBitcoinAddress("Invoice 42")
+	assertNil(t, err)
+	assertEqual(t, address.Label, "Invoice 42")
+	assertEqual(t, address.TotalConfirmed.String(), "0.0")
+	assertEqual(t, address.TotalReceived.String(), "0.0")
+	assertEqual(t, address.Address, "mrhz5ZgSF3C1BSdyCKt3gEdhKoRL5BNfJV")
+}
+
+func TestUpdateBitcoinAddressLabel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/bitcoin_addresses/mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR.json" {
+			assertEqual(t, r.Method, "PUT")
+			assertEqual(t, r.FormValue("bitcoin_address[label]"), "Customer 7")
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `
+				{
+					"bitcoin_address": {
+						"label": "Customer 7",
+						"total_confirmed": "21.71364123",
+						"total_received": "21.71364124",
+						"address": "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR"
+					}
+				}
+			`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	address, err := client.UpdateBitcoinAddressLabel("mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR", "Customer 7")
+	assertNil(t, err)
+	assertEqual(t, address.Label, "Customer 7")
+	assertEqual(t, address.TotalReceived.String(), "21.71364124")
+	assertEqual(t, address.Address, "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
+
+	_, err = client.UpdateBitcoinAddressLabel("", "Customer 7")
+	assertNotNil(t, err)
+}
+
 func TestContacts(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
@@ -221,22 +290,22 @@ func TestContacts(t *testing.T) {
 
 	{
 		contact := contacts[0]
-		assertEqual(t, contact.UpdatedAt, "2013-06-25T17:58:23.000+10:00")
+		assertEqual(t, contact.UpdatedAt.String(), "2013-06-25T17:58:23.000+10:00")
 		assertEqual(t, contact.UUID, "e359fd02-0079-4ef0-9f1f-706a84f39cca")
 		assertEqual(t, contact.Name, "Ryan Zhou")
 		assertEqual(t, contact.PayeeName, "ryan@coinjar.io")
 		assertEqual(t, contact.PayeeType, "WALLET")
-		assertEqual(t, contact.CreatedAt, "2013-06-25T17:58:23.000+10:00")
+		assertEqual(t, contact.CreatedAt.String(), "2013-06-25T17:58:23.000+10:00")
 	}
 
 	{
 		contact := contacts[1]
-		assertEqual(t, contact.UpdatedAt, "2013-06-25T17:58:17.000+10:00")
+		assertEqual(t, contact.UpdatedAt.String(), "2013-06-25T17:58:17.000+10:00")
 		assertEqual(t, contact.UUID, "e78a2823-a567-41bd-8355-8472ee6fbb89")
 		assertEqual(t, contact.Name, "Jerrold Poh")
 		assertEqual(t, contact.PayeeName, "jerrold@coinjar.io")
 		assertEqual(t, contact.PayeeType, "WALLET")
-		assertEqual(t, contact.CreatedAt, "2013-06-25T17:58:17.000+10:00")
+		assertEqual(t, contact.CreatedAt.String(), "2013-06-25T17:58:17.000+10:00")
 	}
 }
 
@@ -283,22 +352,22 @@ func TestListContacts(t *testing.T) {
 
 	{
 		contact := contacts[0]
-		assertEqual(t, contact.UpdatedAt, "2013-06-25T17:58:23.000+10:00")
+		assertEqual(t, contact.UpdatedAt.String(), "2013-06-25T17:58:23.000+10:00")
 		assertEqual(t, contact.UUID, "e359fd02-0079-4ef0-9f1f-706a84f39cca")
 		assertEqual(t, contact.Name, "Ryan Zhou")
 		assertEqual(t, contact.PayeeName, "ryan@coinjar.io")
 		assertEqual(t, contact.PayeeType, "WALLET")
-		assertEqual(t, contact.CreatedAt, "2013-06-25T17:58:23.000+10:00")
+		assertEqual(t, contact.CreatedAt.String(), "2013-06-25T17:58:23.000+10:00")
 	}
 
 	{
 		contact := contacts[1]
-		assertEqual(t, contact.UpdatedAt, "2013-06-25T17:58:17.000+10:00")
+		assertEqual(t, contact.UpdatedAt.String(), "2013-06-25T17:58:17.000+10:00")
 		assertEqual(t, contact.UUID, "e78a2823-a567-41bd-8355-8472ee6fbb89")
 		assertEqual(t, contact.Name, "Jerrold Poh")
 		assertEqual(t, contact.PayeeName, "jerrold@coinjar.io")
 		assertEqual(t, contact.PayeeType, "WALLET")
-		assertEqual(t, contact.CreatedAt, "2013-06-25T17:58:17.000+10:00")
+		assertEqual(t, contact.CreatedAt.String(), "2013-06-25T17:58:17.000+10:00")
 	}
 }
 
@@ -330,12 +399,145 @@ func TestContact(t *testing.T) {
 	contact, err := client.Contact("e359fd02-0079-4ef0-9f1f-706a84f39cca")
 	assertNil(t, err)
 
-	assertEqual(t, contact.UpdatedAt, "2013-06-25T17:58:23.000+10:00")
+	assertEqual(t, contact.UpdatedAt.String(), "2013-06-25T17:58:23.000+10:00")
 	assertEqual(t, contact.UUID, "e359fd02-0079-4ef0-9f1f-706a84f39cca")
 	assertEqual(t, contact.Name, "Ryan Zhou")
 	assertEqual(t, contact.PayeeName, "ryan@coinjar.io")
 	assertEqual(t, contact.PayeeType, "WALLET")
-	assertEqual(t, contact.CreatedAt, "2013-06-25T17:58:23.000+10:00")
+	assertEqual(t, contact.CreatedAt.String(), "2013-06-25T17:58:23.000+10:00")
+	assertEqual(t, contact.CreatedAt.UTC(), time.Date(2013, 6, 25, 7, 58, 23, 0, time.UTC))
+}
+
+func TestCreateContact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/contacts.json" {
+			assertEqual(t, r.Method, "POST")
+			assertEqual(t, r.FormValue("contact[name]"), "Ryan Zhou")
+			assertEqual(t, r.FormValue("contact[payee_name]"), "ryan@coinjar.io")
+			assertEqual(t, r.FormValue("contact[payee_type]"), "WALLET")
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `
+				{
+					"contact": {
+						"updated_at": "2014-04-01T10:00:00.000+10:00",
+						"uuid": "e359fd02-0079-4ef0-9f1f-706a84f39cca",
+						"name": "Ryan Zhou",
+						"payee_name": "ryan@coinjar.io",
+						"payee_type": "WALLET",
+						"created_at": "2014-04-01T10:00:00.000+10:00"
+					}
+				}
+			`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	contact, err := client.CreateContact(Contact{
+		Name:      "Ryan Zhou",
+		PayeeName: "ryan@coinjar.io",
+		PayeeType: PayeeTypeWallet,
+	})
+	assertNil(t, err)
+	assertEqual(t, contact.UUID, "e359fd02-0079-4ef0-9f1f-706a84f39cca")
+	assertEqual(t, contact.Name, "Ryan Zhou")
+	assertEqual(t, contact.PayeeType, "WALLET")
+}
+
+func TestCreateContactValidation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request should not have been sent: %v", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	_, err := client.CreateContact(Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: "EMAIL"})
+	assertEqual(t, err.Error(), `coinjar: invalid contact payee type "EMAIL"`)
+	_, err = client.CreateContact(Contact{PayeeName: "ryan@coinjar.io", PayeeType: PayeeTypeWallet})
+	assertNotNil(t, err)
+	_, err = client.CreateContact(Contact{Name: "Ryan Zhou", PayeeType: PayeeTypeWallet})
+	assertNotNil(t, err)
+	_, err = client.UpdateContact(Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: PayeeTypeWallet})
+	assertNotNil(t, err)
+}
+
+func TestUpdateContact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/contacts/e359fd02-0079-4ef0-9f1f-706a84f39cca.json" {
+			assertEqual(t, r.Method, "PUT")
+			assertEqual(t, r.FormValue("contact[name]"), "Ryan")
+			assertEqual(t, r.FormValue("contact[payee_name]"), "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
+			assertEqual(t, r.FormValue("contact[payee_type]"), "ADDRESS")
+			fmt.Fprint(w, `{"contact": {"uuid": "e359fd02-0079-4ef0-9f1f-706a84f39cca", "name": "Ryan", "payee_type": "ADDRESS"}}`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	contact, err := client.UpdateContact(Contact{
+		UUID:      "e359fd02-0079-4ef0-9f1f-706a84f39cca",
+		Name:      "Ryan",
+		PayeeName: "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+		PayeeType: PayeeTypeBitcoinAddress,
+	})
+	assertNil(t, err)
+	assertEqual(t, contact.Name, "Ryan")
+	assertEqual(t, contact.PayeeType, "ADDRESS")
+}
+
+func TestDeleteContact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/contacts/e359fd02-0079-4ef0-9f1f-706a84f39cca.json" {
+			assertEqual(t, r.Method, "DELETE")
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	assertNil(t, client.DeleteContact("e359fd02-0079-4ef0-9f1f-706a84f39cca"))
+	assertEqual(t, errors.Is(client.DeleteContact("missing"), ErrNotFound), true)
+	assertNotNil(t, client.DeleteContact(""))
+}
+
+func TestUpsertContact(t *testing.T) {
+	var calls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /contacts.json":
+			fmt.Fprint(w, `{"contacts": [{"uuid": "e359fd02-0079-4ef0-9f1f-706a84f39cca", "name": "Ryan Zhou"}]}`)
+		case "PUT /contacts/e359fd02-0079-4ef0-9f1f-706a84f39cca.json":
+			fmt.Fprintf(w, `{"contact": {"uuid": "e359fd02-0079-4ef0-9f1f-706a84f39cca", "name": %q}}`, r.FormValue("contact[name]"))
+		case "POST /contacts.json":
+			fmt.Fprintf(w, `{"contact": {"uuid": "9a7b1c4e-44c5-4a5b-b6a8-3f44c2f0a1d2", "name": %q}}`, r.FormValue("contact[name]"))
+		default:
+			t.Errorf("Requested unexpected endpoint: %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	contact, err := client.UpsertContact(Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: PayeeTypeWallet})
+	assertNil(t, err)
+	assertEqual(t, contact.UUID, "e359fd02-0079-4ef0-9f1f-706a84f39cca")
+
+	contact, err = client.UpsertContact(Contact{Name: "Jerrold", PayeeName: "jerrold@coinjar.io", PayeeType: PayeeTypeWallet})
+	assertNil(t, err)
+	assertEqual(t, contact.UUID, "9a7b1c4e-44c5-4a5b-b6a8-3f44c2f0a1d2")
+	assertEqual(t, contact.Name, "Jerrold")
+
+	assertEqual(t, strings.Join(calls, ", "), "GET /contacts.json, PUT /contacts/e359fd02-0079-4ef0-9f1f-706a84f39cca.json, GET /contacts.json, POST /contacts.json")
 }
 
 func TestPayments(t *testing.T) {
@@ -413,58 +615,58 @@ func TestPayments(t *testing.T) {
 
 	{
 		payment := payments[0]
-		assertEqual(t, payment.Status, "COMPLETED")
-		assertEqual(t, payment.Amount, "1.25")
+		assertEqual(t, payment.Status, PaymentCompleted)
+		assertEqual(t, payment.Amount.String(), "1.25")
 		// assertEqual(t, payment.Reference, nil)
-		assertEqual(t, payment.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, payment.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, payment.UUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 		assertEqual(t, payment.PayeeName, "jerrold@coinjar.io")
 		assertEqual(t, payment.PayeeType, "WALLET")
-		assertEqual(t, payment.CreatedAt, "2013-06-19T12:06:53.000+10:00")
+		assertEqual(t, payment.CreatedAt.String(), "2013-06-19T12:06:53.000+10:00")
 
 		related := payment.RelatedTransaction
 		assertNotNil(t, related)
-		// assertEqual(t, related.Confirmations, nil)
-		assertEqual(t, related.Status, "SENT")
-		assertEqual(t, related.Amount, "-1.25")
+		assertEqual(t, related.Confirmations, 0)
+		assertEqual(t, related.Status, TransactionSent)
+		assertEqual(t, related.Amount.String(), "-1.25")
 		// assertEqual(t, related.Reference, nil)
 		assertEqual(t, related.UserID, 1)
 		assertEqual(t, related.CounterpartyUserID, 3)
-		assertEqual(t, related.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, related.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, related.UUID, "880b8337-f262-460b-a762-6193f1b0ec33")
 		// assertEqual(t, related.BitcoinTxid, nil)
 		// assertEqual(t, related.CounterpartyAddress, nil)
 		assertEqual(t, related.ID, 10018)
 		assertEqual(t, related.PaymentID, 9590)
-		assertEqual(t, related.CreatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, related.CreatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	}
 
 	{
 		payment := payments[1]
-		assertEqual(t, payment.Status, "COMPLETED")
-		assertEqual(t, payment.Amount, "0.01")
+		assertEqual(t, payment.Status, PaymentCompleted)
+		assertEqual(t, payment.Amount.String(), "0.01")
 		// assertEqual(t, payment.Reference, nil)
-		assertEqual(t, payment.UpdatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, payment.UpdatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 		assertEqual(t, payment.UUID, "0b0db001-b64f-4cf9-ae44-1bafc0ee55a2")
 		assertEqual(t, payment.PayeeName, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
 		assertEqual(t, payment.PayeeType, "ADDRESS")
-		assertEqual(t, payment.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, payment.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 
 		related := payment.RelatedTransaction
 		assertNotNil(t, related)
-		// assertEqual(t, related.Confirmations, nil)
-		assertEqual(t, related.Status, "SENT")
-		assertEqual(t, related.Amount, "-0.01")
+		assertEqual(t, related.Confirmations, 0)
+		assertEqual(t, related.Status, TransactionSent)
+		assertEqual(t, related.Amount.String(), "-0.01")
 		// assertEqual(t, related.Reference, nil)
 		assertEqual(t, related.UserID, 1)
 		// assertEqual(t, related.CounterpartyUserID, nil)
-		assertEqual(t, related.UpdatedAt, "2013-05-14T15:36:30.000+10:00")
+		assertEqual(t, related.UpdatedAt.String(), "2013-05-14T15:36:30.000+10:00")
 		assertEqual(t, related.UUID, "21691054-033a-40e3-abaf-85948c9aba44")
 		assertEqual(t, related.BitcoinTxid, "c0bd705d19d329ac19a35996e2fde8da2cd6603d49c9a9775c12803bf1c75922")
 		assertEqual(t, related.CounterpartyAddress, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
 		assertEqual(t, related.ID, 9630)
 		assertEqual(t, related.PaymentID, 9416)
-		assertEqual(t, related.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, related.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 	}
 }
 
@@ -545,58 +747,58 @@ func TestListPayments(t *testing.T) {
 
 	{
 		payment := payments[0]
-		assertEqual(t, payment.Status, "COMPLETED")
-		assertEqual(t, payment.Amount, "1.25")
+		assertEqual(t, payment.Status, PaymentCompleted)
+		assertEqual(t, payment.Amount.String(), "1.25")
 		// assertEqual(t, payment.Reference, nil)
-		assertEqual(t, payment.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, payment.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, payment.UUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 		assertEqual(t, payment.PayeeName, "jerrold@coinjar.io")
 		assertEqual(t, payment.PayeeType, "WALLET")
-		assertEqual(t, payment.CreatedAt, "2013-06-19T12:06:53.000+10:00")
+		assertEqual(t, payment.CreatedAt.String(), "2013-06-19T12:06:53.000+10:00")
 
 		related := payment.RelatedTransaction
 		assertNotNil(t, related)
-		// assertEqual(t, related.Confirmations, nil)
-		assertEqual(t, related.Status, "SENT")
-		assertEqual(t, related.Amount, "-1.25")
+		assertEqual(t, related.Confirmations, 0)
+		assertEqual(t, related.Status, TransactionSent)
+		assertEqual(t, related.Amount.String(), "-1.25")
 		// assertEqual(t, related.Reference, nil)
 		assertEqual(t, related.UserID, 1)
 		assertEqual(t, related.CounterpartyUserID, 3)
-		assertEqual(t, related.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, related.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, related.UUID, "880b8337-f262-460b-a762-6193f1b0ec33")
 		// assertEqual(t, related.BitcoinTxid, nil)
 		// assertEqual(t, related.CounterpartyAddress, nil)
 		assertEqual(t, related.ID, 10018)
 		assertEqual(t, related.PaymentID, 9590)
-		assertEqual(t, related.CreatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, related.CreatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	}
 
 	{
 		payment := payments[1]
-		assertEqual(t, payment.Status, "COMPLETED")
-		assertEqual(t, payment.Amount, "0.01")
+		assertEqual(t, payment.Status, PaymentCompleted)
+		assertEqual(t, payment.Amount.String(), "0.01")
 		// assertEqual(t, payment.Reference, nil)
-		assertEqual(t, payment.UpdatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, payment.UpdatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 		assertEqual(t, payment.UUID, "0b0db001-b64f-4cf9-ae44-1bafc0ee55a2")
 		assertEqual(t, payment.PayeeName, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
 		assertEqual(t, payment.PayeeType, "ADDRESS")
-		assertEqual(t, payment.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, payment.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 
 		related := payment.RelatedTransaction
 		assertNotNil(t, related)
-		// assertEqual(t, related.Confirmations, nil)
-		assertEqual(t, related.Status, "SENT")
-		assertEqual(t, related.Amount, "-0.01")
+		assertEqual(t, related.Confirmations, 0)
+		assertEqual(t, related.Status, TransactionSent)
+		assertEqual(t, related.Amount.String(), "-0.01")
 		// assertEqual(t, related.Reference, nil)
 		assertEqual(t, related.UserID, 1)
 		// assertEqual(t, related.CounterpartyUserID, nil)
-		assertEqual(t, related.UpdatedAt, "2013-05-14T15:36:30.000+10:00")
+		assertEqual(t, related.UpdatedAt.String(), "2013-05-14T15:36:30.000+10:00")
 		assertEqual(t, related.UUID, "21691054-033a-40e3-abaf-85948c9aba44")
 		assertEqual(t, related.BitcoinTxid, "c0bd705d19d329ac19a35996e2fde8da2cd6603d49c9a9775c12803bf1c75922")
 		assertEqual(t, related.CounterpartyAddress, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
 		assertEqual(t, related.ID, 9630)
 		assertEqual(t, related.PaymentID, 9416)
-		assertEqual(t, related.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, related.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 	}
 }
 
@@ -645,30 +847,156 @@ func TestPayment(t *testing.T) {
 	payment, err := client.Payment("d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 	assertNil(t, err)
 
-	assertEqual(t, payment.Status, "COMPLETED")
-	assertEqual(t, payment.Amount, "1.25")
+	assertEqual(t, payment.Status, PaymentCompleted)
+	assertEqual(t, payment.Amount.String(), "1.25")
 	// assertEqual(t, payment.Reference, nil)
-	assertEqual(t, payment.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+	assertEqual(t, payment.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	assertEqual(t, payment.UUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 	assertEqual(t, payment.PayeeName, "jerrold@coinjar.io")
 	assertEqual(t, payment.PayeeType, "WALLET")
-	assertEqual(t, payment.CreatedAt, "2013-06-19T12:06:53.000+10:00")
+	assertEqual(t, payment.CreatedAt.String(), "2013-06-19T12:06:53.000+10:00")
 
 	related := payment.RelatedTransaction
 	assertNotNil(t, related)
-	// assertEqual(t, related.Confirmations, nil)
-	assertEqual(t, related.Status, "SENT")
-	assertEqual(t, related.Amount, "-1.25")
+	assertEqual(t, related.Confirmations, 0)
+	assertEqual(t, related.Status, TransactionSent)
+	assertEqual(t, related.Amount.String(), "-1.25")
 	// assertEqual(t, related.Reference, nil)
 	assertEqual(t, related.UserID, 1)
 	assertEqual(t, related.CounterpartyUserID, 3)
-	assertEqual(t, related.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+	assertEqual(t, related.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	assertEqual(t, related.UUID, "880b8337-f262-460b-a762-6193f1b0ec33")
 	// assertEqual(t, related.BitcoinTxid, nil)
 	// assertEqual(t, related.CounterpartyAddress, nil)
 	assertEqual(t, related.ID, 10018)
 	assertEqual(t, related.PaymentID, 9590)
-	assertEqual(t, related.CreatedAt, "2013-06-19T12:06:54.000+10:00")
+	assertEqual(t, related.CreatedAt.String(), "2013-06-19T12:06:54.000+10:00")
+}
+
+func TestCreatePayment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/payments.json" {
+			assertEqual(t, r.Method, "POST")
+			assertEqual(t, r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+			assertEqual(t, r.FormValue("payment[payee_name]"), "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
+			assertEqual(t, r.FormValue("payment[amount]"), "0.5")
+			assertEqual(t, r.FormValue("payment[currency]"), "BTC")
+			assertEqual(t, r.FormValue("payment[reference]"), "Invoice 42")
+			_, ok := r.Form["payment[contact_uuid]"]
+			assertEqual(t, ok, false)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `
+				{
+					"payment": {
+						"status": "PENDING",
+						"related_transaction": null,
+						"amount": "0.5",
+						"reference": "Invoice 42",
+						"updated_at": "2014-04-01T10:00:00.000+10:00",
+						"uuid": "7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36",
+						"payee_name": "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+						"payee_type": "ADDRESS",
+						"created_at": "2014-04-01T10:00:00.000+10:00"
+					}
+				}
+			`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	payment, err := client.CreatePayment(PaymentRequest{
+		PayeeName: "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+		Amount:    MustParseAmount("0.5"),
+		Currency:  "BTC",
+		Reference: "Invoice 42",
+	})
+	assertNil(t, err)
+
+	assertEqual(t, payment.Status, PaymentPending)
+	assertEqual(t, payment.Amount.String(), "0.5")
+	assertEqual(t, payment.Reference, "Invoice 42")
+	assertEqual(t, payment.UUID, "7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36")
+	assertEqual(t, payment.PayeeType, "ADDRESS")
+	assertEqual(t, payment.RelatedTransaction, (*Transaction)(nil))
+}
+
+func TestCreatePaymentToContact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, r.Method, "POST")
+		assertEqual(t, r.FormValue("payment[contact_uuid]"), "e359fd02-0079-4ef0-9f1f-706a84f39cca")
+		assertEqual(t, r.FormValue("payment[amount]"), "1.25")
+		_, ok := r.Form["payment[payee_name]"]
+		assertEqual(t, ok, false)
+		fmt.Fprint(w, `{"payment": {"status": "PENDING", "uuid": "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434"}}`)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	payment, err := client.CreatePayment(PaymentRequest{
+		ContactUUID: "e359fd02-0079-4ef0-9f1f-706a84f39cca",
+		Amount:      MustParseAmount("1.25"),
+	})
+	assertNil(t, err)
+	assertEqual(t, payment.UUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
+}
+
+func TestCreatePaymentValidation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request should not have been sent: %v", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	_, err := client.CreatePayment(PaymentRequest{Amount: MustParseAmount("1.0")})
+	assertNotNil(t, err)
+	_, err = client.CreatePayment(PaymentRequest{PayeeName: "ryan@coinjar.io", ContactUUID: "e359fd02", Amount: MustParseAmount("1.0")})
+	assertNotNil(t, err)
+	_, err = client.CreatePayment(PaymentRequest{PayeeName: "ryan@coinjar.io"})
+	assertNotNil(t, err)
+}
+
+func TestConfirmPayment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/payments/7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36/confirm.json" {
+			assertEqual(t, r.Method, "PUT")
+			fmt.Fprint(w, `{"payment": {"status": "COMPLETED", "uuid": "7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36"}}`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	payment, err := client.ConfirmPayment("7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36")
+	assertNil(t, err)
+	assertEqual(t, payment.Status, PaymentCompleted)
+	_, err = client.ConfirmPayment("")
+	assertNotNil(t, err)
+}
+
+func TestCancelPayment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo")
+		if url := r.URL.Path; url == "/payments/7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36/cancel.json" {
+			assertEqual(t, r.Method, "PUT")
+			fmt.Fprint(w, `{"payment": {"status": "CANCELLED", "uuid": "7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36"}}`)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("pJ451Sk8tXz9LdUbGg1sobLUZuVzuJwdyr4sD3owFW4WYHxo", ts.URL)
+	payment, err := client.CancelPayment("7c6e9c1c-4a49-4cc1-a4f2-2be1d4f06f36")
+	assertNil(t, err)
+	assertEqual(t, payment.Status, PaymentCancelled)
+	_, err = client.CancelPayment("")
+	assertNotNil(t, err)
 }
 
 func TestTransactions(t *testing.T) {
@@ -722,32 +1050,32 @@ func TestTransactions(t *testing.T) {
 
 	{
 		transaction := transactions[0]
-		// assertEqual(t, transaction.Confirmations, nil)
-		assertEqual(t, transaction.Status, "SENT")
-		assertEqual(t, transaction.Amount, "-1.25")
+		assertEqual(t, transaction.Confirmations, 0)
+		assertEqual(t, transaction.Status, TransactionSent)
+		assertEqual(t, transaction.Amount.String(), "-1.25")
 		// assertEqual(t, transaction.Reference, nil)
 		assertEqual(t, transaction.CounterpartyType, "Sent to")
-		assertEqual(t, transaction.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, transaction.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, transaction.UUID, "880b8337-f262-460b-a762-6193f1b0ec33")
 		// assertEqual(t, transaction.BitcoinTxid, nil)
 		assertEqual(t, transaction.RelatedPaymentUUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 		assertEqual(t, transaction.CounterpartyName, "jerrold@coinjar.io")
-		assertEqual(t, transaction.CreatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, transaction.CreatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	}
 
 	{
 		transaction := transactions[1]
-		// assertEqual(t, transaction.Confirmations, nil)
-		assertEqual(t, transaction.Status, "SENT")
-		assertEqual(t, transaction.Amount, "-0.01")
+		assertEqual(t, transaction.Confirmations, 0)
+		assertEqual(t, transaction.Status, TransactionSent)
+		assertEqual(t, transaction.Amount.String(), "-0.01")
 		// assertEqual(t, transaction.Reference, nil)
 		assertEqual(t, transaction.CounterpartyType, "Sent to")
-		assertEqual(t, transaction.UpdatedAt, "2013-05-14T15:36:30.000+10:00")
+		assertEqual(t, transaction.UpdatedAt.String(), "2013-05-14T15:36:30.000+10:00")
 		assertEqual(t, transaction.UUID, "21691054-033a-40e3-abaf-85948c9aba44")
 		assertEqual(t, transaction.BitcoinTxid, "c0bd705d19d329ac19a35996e2fde8da2cd6603d49c9a9775c12803bf1c75922")
 		assertEqual(t, transaction.RelatedPaymentUUID, "0b0db001-b64f-4cf9-ae44-1bafc0ee55a2")
 		assertEqual(t, transaction.CounterpartyName, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
-		assertEqual(t, transaction.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, transaction.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 	}
 }
 
@@ -804,32 +1132,32 @@ func TestListTransactions(t *testing.T) {
 
 	{
 		transaction := transactions[0]
-		// assertEqual(t, transaction.Confirmations, nil)
-		assertEqual(t, transaction.Status, "SENT")
-		assertEqual(t, transaction.Amount, "-1.25")
+		assertEqual(t, transaction.Confirmations, 0)
+		assertEqual(t, transaction.Status, TransactionSent)
+		assertEqual(t, transaction.Amount.String(), "-1.25")
 		// assertEqual(t, transaction.Reference, nil)
 		assertEqual(t, transaction.CounterpartyType, "Sent to")
-		assertEqual(t, transaction.UpdatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, transaction.UpdatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 		assertEqual(t, transaction.UUID, "880b8337-f262-460b-a762-6193f1b0ec33")
 		// assertEqual(t, transaction.BitcoinTxid, nil)
 		assertEqual(t, transaction.RelatedPaymentUUID, "d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
 		assertEqual(t, transaction.CounterpartyName, "jerrold@coinjar.io")
-		assertEqual(t, transaction.CreatedAt, "2013-06-19T12:06:54.000+10:00")
+		assertEqual(t, transaction.CreatedAt.String(), "2013-06-19T12:06:54.000+10:00")
 	}
 
 	{
 		transaction := transactions[1]
-		// assertEqual(t, transaction.Confirmations, nil)
-		assertEqual(t, transaction.Status, "SENT")
-		assertEqual(t, transaction.Amount, "-0.01")
+		assertEqual(t, transaction.Confirmations, 0)
+		assertEqual(t, transaction.Status, TransactionSent)
+		assertEqual(t, transaction.Amount.String(), "-0.01")
 		// assertEqual(t, transaction.Reference, nil)
 		assertEqual(t, transaction.CounterpartyType, "Sent to")
-		assertEqual(t, transaction.UpdatedAt, "2013-05-14T15:36:30.000+10:00")
+		assertEqual(t, transaction.UpdatedAt.String(), "2013-05-14T15:36:30.000+10:00")
 		assertEqual(t, transaction.UUID, "21691054-033a-40e3-abaf-85948c9aba44")
 		assertEqual(t, transaction.BitcoinTxid, "c0bd705d19d329ac19a35996e2fde8da2cd6603d49c9a9775c12803bf1c75922")
 		assertEqual(t, transaction.RelatedPaymentUUID, "0b0db001-b64f-4cf9-ae44-1bafc0ee55a2")
 		assertEqual(t, transaction.CounterpartyName, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
-		assertEqual(t, transaction.CreatedAt, "2013-05-14T14:34:32.000+10:00")
+		assertEqual(t, transaction.CreatedAt.String(), "2013-05-14T14:34:32.000+10:00")
 	}
 }
 
@@ -866,17 +1194,17 @@ func TestTransaction(t *testing.T) {
 	transaction, err := client.Transaction("3eb68998-8eb5-44a8-a115-49a383dcecfa")
 	assertNil(t, err)
 
-	// assertEqual(t, transaction.Confirmations, nil)
-	assertEqual(t, transaction.Status, "SENT")
-	assertEqual(t, transaction.Amount, "-0.01")
+	assertEqual(t, transaction.Confirmations, 0)
+	assertEqual(t, transaction.Status, TransactionSent)
+	assertEqual(t, transaction.Amount.String(), "-0.01")
 	// assertEqual(t, transaction.Reference, nil)
 	assertEqual(t, transaction.CounterpartyType, "Sent to")
-	assertEqual(t, transaction.UpdatedAt, "2013-05-14T14:17:05.000+10:00")
+	assertEqual(t, transaction.UpdatedAt.String(), "2013-05-14T14:17:05.000+10:00")
 	assertEqual(t, transaction.UUID, "3eb68998-8eb5-44a8-a115-49a383dcecfa")
 	assertEqual(t, transaction.BitcoinTxid, "318d752be29ae839bf79f12d47314b0d6e60174eff6720b6cf23853d76d36583")
 	assertEqual(t, transaction.RelatedPaymentUUID, "f86c3672-a16f-4198-89d3-24ab85f59b5b")
 	assertEqual(t, transaction.CounterpartyName, "msiu1k3tmJjiXZ1ptfoWRuVJ6V3JNS19Ho")
-	assertEqual(t, transaction.CreatedAt, "2013-05-14T14:17:00.000+10:00")
+	assertEqual(t, transaction.CreatedAt.String(), "2013-05-14T14:17:00.000+10:00")
 }
 
 func TestFairRate(t *testing.T) {
@@ -902,9 +1230,46 @@ func TestFairRate(t *testing.T) {
 	rate, err := client.FairRate("USD")
 	assertNil(t, err)
 
-	assertEqual(t, rate.Bid, "101.4713")
-	assertEqual(t, rate.Ask, "103.5213")
-	assertEqual(t, rate.Spot, "102.4963")
+	assertEqual(t, rate.Bid.String(), "101.4713")
+	assertEqual(t, rate.Ask.String(), "103.5213")
+	assertEqual(t, rate.Spot.String(), "102.4963")
+}
+
+func TestAccountContextCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request should not have been sent: %v", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	user, err := client.AccountContext(ctx)
+	assertEqual(t, user, (*User)(nil))
+	assertEqual(t, errors.Is(err, context.Canceled), true)
+}
+
+func TestTransactionsContextDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		assertEqual(t, r.URL.Path, "/transactions.json")
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	transactions, err := client.TransactionsContext(ctx)
+	assertEqual(t, len(transactions), 0)
+	assertEqual(t, errors.Is(err, context.DeadlineExceeded), true)
 }
 
 func assertRequestUsesApiKey(t *testing.T, r *http.Request, key string) {
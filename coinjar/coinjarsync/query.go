@@ -0,0 +1,89 @@
+package coinjarsync
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// The query functions read a store without touching the API. A nil filter
+// matches every record. Transactions and payments come back oldest first,
+// contacts and bitcoin addresses in key order.
+
+func QueryTransactions(store Store, filter func(coinjar.Transaction) bool) ([]coinjar.Transaction, error) {
+	var out []coinjar.Transaction
+	err := store.Each(Transactions, func(_ string, data []byte) error {
+		var t coinjar.Transaction
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		if filter == nil || filter(t) {
+			out = append(out, t)
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt.Time) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt.Time)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, err
+}
+
+func QueryPayments(store Store, filter func(coinjar.Payment) bool) ([]coinjar.Payment, error) {
+	var out []coinjar.Payment
+	err := store.Each(Payments, func(_ string, data []byte) error {
+		var p coinjar.Payment
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		if filter == nil || filter(p) {
+			out = append(out, p)
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt.Time) })
+	return out, err
+}
+
+func QueryContacts(store Store, filter func(coinjar.Contact) bool) ([]coinjar.Contact, error) {
+	var out []coinjar.Contact
+	err := store.Each(Contacts, func(_ string, data []byte) error {
+		var c coinjar.Contact
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		if filter == nil || filter(c) {
+			out = append(out, c)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func QueryBitcoinAddresses(store Store, filter func(coinjar.BitcoinAddress) bool) ([]coinjar.BitcoinAddress, error) {
+	var out []coinjar.BitcoinAddress
+	err := store.Each(BitcoinAddresses, func(_ string, data []byte) error {
+		var a coinjar.BitcoinAddress
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		if filter == nil || filter(a) {
+			out = append(out, a)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Lookup decodes the record stored under key into v, returning
+// coinjar.ErrNotFound if there is none.
+func Lookup(store Store, kind Kind, key string, v interface{}) error {
+	data, err := store.Get(kind, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,229 @@
+package coinjarsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// Kind names a mirrored collection.
+type Kind string
+
+const (
+	Transactions     Kind = "transactions"
+	Payments         Kind = "payments"
+	Contacts         Kind = "contacts"
+	BitcoinAddresses Kind = "bitcoin_addresses"
+)
+
+var kinds = []Kind{Transactions, Payments, Contacts, BitcoinAddresses}
+
+// Mark is the high-water mark of a collection: how far the last sync got.
+type Mark struct {
+	// UpdatedAt and ID are those of the most recently updated record seen.
+	UpdatedAt time.Time `json:"updated_at"`
+	ID        int       `json:"id,omitempty"`
+	// Offset is the number of records walked, where the next sync resumes
+	// when the API lists oldest first.
+	Offset int       `json:"offset"`
+	Synced time.Time `json:"synced"`
+}
+
+func (m Mark) before(updatedAt time.Time, id int) bool {
+	if !updatedAt.Equal(m.UpdatedAt) {
+		return m.UpdatedAt.Before(updatedAt)
+	}
+	return m.ID < id
+}
+
+// Store holds mirrored records as their JSON encoding, keyed by UUID, or by
+// address for bitcoin addresses. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Get returns coinjar.ErrNotFound when there is no record under key.
+	Get(kind Kind, key string) ([]byte, error)
+	Put(kind Kind, key string, record []byte) error
+	Delete(kind Kind, key string) error
+	// Each calls fn for every record of kind, in key order, stopping at the
+	// first error.
+	Each(kind Kind, fn func(key string, record []byte) error) error
+	Mark(kind Kind) (Mark, error)
+	SetMark(kind Kind, mark Mark) error
+	// Flush makes every change so far durable. The syncer calls it once at
+	// the end of each sync.
+	Flush() error
+}
+
+// MemoryStore is a Store that lives only as long as the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[Kind]map[string][]byte
+	marks   map[Kind]Mark
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[Kind]map[string][]byte),
+		marks:   make(map[Kind]Mark),
+	}
+}
+
+func (s *MemoryStore) Get(kind Kind, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[kind][key]
+	if !ok {
+		return nil, coinjar.ErrNotFound
+	}
+	return record, nil
+}
+
+func (s *MemoryStore) Put(kind Kind, key string, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records[kind] == nil {
+		s.records[kind] = make(map[string][]byte)
+	}
+	s.records[kind][key] = append([]byte(nil), record...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(kind Kind, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records[kind], key)
+	return nil
+}
+
+// Each iterates over a snapshot, so fn may call back into the store.
+func (s *MemoryStore) Each(kind Kind, fn func(key string, record []byte) error) error {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.records[kind]))
+	for key := range s.records[kind] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := make([][]byte, len(keys))
+	for i, key := range keys {
+		records[i] = s.records[kind][key]
+	}
+	s.mu.Unlock()
+
+	for i, key := range keys {
+		if err := fn(key, records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Mark(kind Kind) (Mark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.marks[kind], nil
+}
+
+func (s *MemoryStore) SetMark(kind Kind, mark Mark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marks[kind] = mark
+	return nil
+}
+
+func (s *MemoryStore) Flush() error { return nil }
+
+// FileStore is a Store kept in a directory: one JSON file per collection and
+// marks.json for the high-water marks. It holds everything in memory and
+// rewrites the files on Flush, replacing each atomically.
+type FileStore struct {
+	MemoryStore
+	dir string
+}
+
+// OpenFileStore loads the store in dir, creating the directory if needed.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir}
+	s.records = make(map[Kind]map[string][]byte)
+	s.marks = make(map[Kind]Mark)
+	for _, kind := range kinds {
+		records := make(map[string]json.RawMessage)
+		if err := s.load(string(kind)+".json", &records); err != nil {
+			return nil, err
+		}
+		s.records[kind] = make(map[string][]byte, len(records))
+		for key, record := range records {
+			// Flush indents the file, so compact records back to the form
+			// they were put in.
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, record); err != nil {
+				return nil, err
+			}
+			s.records[kind][key] = buf.Bytes()
+		}
+	}
+	if err := s.load("marks.json", &s.marks); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) load(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("coinjarsync: %s: %v", filepath.Join(s.dir, name), err)
+	}
+	return nil
+}
+
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, kind := range kinds {
+		records := make(map[string]json.RawMessage, len(s.records[kind]))
+		for key, record := range s.records[kind] {
+			records[key] = record
+		}
+		if err := s.save(string(kind)+".json", records); err != nil {
+			return err
+		}
+	}
+	return s.save("marks.json", s.marks)
+}
+
+func (s *FileStore) save(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, name+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
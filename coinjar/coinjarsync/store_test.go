@@ -0,0 +1,60 @@
+package coinjarsync
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+func TestFileStorePersists(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	received := fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("0.5"), Status: coinjar.TransactionReceived})
+	fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+
+	dir, err := ioutil.TempDir("", "coinjarsync")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := OpenFileStore(dir)
+	assertNil(t, err)
+	_, err = New(fake.Client(), store, nil).Sync(context.Background())
+	assertNil(t, err)
+
+	reopened, err := OpenFileStore(dir)
+	assertNil(t, err)
+	var transaction coinjar.Transaction
+	assertNil(t, Lookup(reopened, Transactions, received.UUID, &transaction))
+	assertEqual(t, transaction.Amount.String(), "0.5")
+	mark, err := reopened.Mark(Transactions)
+	assertNil(t, err)
+	assertEqual(t, mark.Offset, 1)
+
+	// Records read back from disk compare equal to freshly fetched ones.
+	result, err := New(fake.Client(), reopened, nil).Sync(context.Background())
+	assertNil(t, err)
+	assertEqual(t, result.Transactions.Empty(), true)
+	assertEqual(t, result.Contacts.Empty(), true)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	_, err := store.Get(Contacts, "a")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+
+	assertNil(t, store.Put(Contacts, "b", []byte(`{"uuid":"b"}`)))
+	assertNil(t, store.Put(Contacts, "a", []byte(`{"uuid":"a"}`)))
+	var keys []string
+	assertNil(t, store.Each(Contacts, func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return store.Delete(Contacts, key)
+	}))
+	assertEqual(t, len(keys), 2)
+	assertEqual(t, keys[0], "a")
+	_, err = store.Get(Contacts, "b")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+}
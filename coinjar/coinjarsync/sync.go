@@ -0,0 +1,351 @@
+// Package coinjarsync mirrors an account's transactions, payments, contacts
+// and bitcoin addresses into a local Store, fetching only what changed since
+// the last run, so they can be queried offline.
+//
+//	store, err := coinjarsync.OpenFileStore("coinjar-data")
+//	result, err := coinjarsync.New(client, store, nil).Sync(ctx)
+//	pending, err := coinjarsync.QueryTransactions(store, func(t coinjar.Transaction) bool {
+//		return t.Status.IsPending()
+//	})
+package coinjarsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+type Options struct {
+	// PageSize is the number of records requested per call. Defaults to
+	// coinjar.DefaultPageSize, and is at most coinjar.MaxPageSize.
+	PageSize int
+	// NewestFirst says the API lists transactions and payments newest first.
+	// A sync then walks from the start and stops at the first page that holds
+	// nothing newer than the high-water mark. Otherwise it resumes from the
+	// offset where the last sync ended.
+	NewestFirst bool
+}
+
+// Changes lists the keys of records a sync added, updated or removed.
+type Changes struct {
+	New, Changed, Deleted []string
+}
+
+func (c *Changes) Empty() bool {
+	return len(c.New)+len(c.Changed)+len(c.Deleted) == 0
+}
+
+type Result struct {
+	Transactions, Payments, Contacts, BitcoinAddresses Changes
+}
+
+// Syncer mirrors one account into one store.
+//
+// Transactions and payments are walked incrementally. Records that can still
+// change, those with a pending status, are refetched one by one on every
+// sync. Contacts and bitcoin addresses are few, so they are walked in full and
+// records the API no longer returns are deleted.
+type Syncer struct {
+	client *coinjar.Client
+	store  Store
+	opts   Options
+	now    func() time.Time
+}
+
+// New returns a Syncer. opts may be nil.
+func New(client *coinjar.Client, store Store, opts *Options) *Syncer {
+	s := &Syncer{client: client, store: store, now: time.Now}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.PageSize <= 0 {
+		s.opts.PageSize = coinjar.DefaultPageSize
+	}
+	if s.opts.PageSize > coinjar.MaxPageSize {
+		s.opts.PageSize = coinjar.MaxPageSize
+	}
+	return s
+}
+
+// record is a fetched resource ready to store.
+type record struct {
+	key       string
+	updatedAt time.Time
+	id        int
+	data      []byte
+}
+
+func newRecord(key string, updatedAt coinjar.Timestamp, id int, v interface{}) (record, error) {
+	data, err := json.Marshal(v)
+	return record{key: key, updatedAt: updatedAt.Time, id: id, data: data}, err
+}
+
+// collection adapts one kind of resource to the sync loop.
+type collection struct {
+	kind Kind
+	list func(ctx context.Context, limit, offset int) ([]record, error)
+	// get and open are set for collections synced incrementally. open reports
+	// whether a stored record may still change.
+	get  func(ctx context.Context, key string) (record, error)
+	open func(data []byte) bool
+}
+
+// Sync brings the store up to date and flushes it. On error the store keeps
+// whatever was applied before the failure, and marks are only advanced for
+// collections that finished.
+func (s *Syncer) Sync(ctx context.Context) (*Result, error) {
+	result := new(Result)
+	for _, c := range []struct {
+		collection
+		changes *Changes
+	}{
+		{s.transactions(), &result.Transactions},
+		{s.payments(), &result.Payments},
+		{s.contacts(), &result.Contacts},
+		{s.bitcoinAddresses(), &result.BitcoinAddresses},
+	} {
+		var err error
+		if c.get != nil {
+			err = s.syncIncremental(ctx, c.collection, c.changes)
+		} else {
+			err = s.syncFull(ctx, c.collection, c.changes)
+		}
+		if err != nil {
+			s.store.Flush()
+			return result, err
+		}
+	}
+	return result, s.store.Flush()
+}
+
+func (s *Syncer) syncIncremental(ctx context.Context, c collection, changes *Changes) error {
+	mark, err := s.store.Mark(c.kind)
+	if err != nil {
+		return err
+	}
+
+	var open []string
+	err = s.store.Each(c.kind, func(key string, data []byte) error {
+		if c.open(data) {
+			open = append(open, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range open {
+		r, err := c.get(ctx, key)
+		if errors.Is(err, coinjar.ErrNotFound) {
+			if err := s.store.Delete(c.kind, key); err != nil {
+				return err
+			}
+			changes.Deleted = append(changes.Deleted, key)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.apply(c.kind, r, changes); err != nil {
+			return err
+		}
+	}
+
+	next := mark
+	offset := 0
+	if !s.opts.NewestFirst {
+		offset = mark.Offset
+	}
+	for {
+		page, err := c.list(ctx, s.opts.PageSize, offset)
+		if err != nil {
+			return err
+		}
+		stale := true
+		for _, r := range page {
+			if err := s.apply(c.kind, r, changes); err != nil {
+				return err
+			}
+			if mark.before(r.updatedAt, r.id) {
+				stale = false
+			}
+			if next.before(r.updatedAt, r.id) {
+				next.UpdatedAt, next.ID = r.updatedAt, r.id
+			}
+		}
+		offset += len(page)
+		if len(page) < s.opts.PageSize || (s.opts.NewestFirst && stale) {
+			break
+		}
+	}
+	if !s.opts.NewestFirst {
+		next.Offset = offset
+	}
+	next.Synced = s.now()
+	return s.store.SetMark(c.kind, next)
+}
+
+func (s *Syncer) syncFull(ctx context.Context, c collection, changes *Changes) error {
+	mark, err := s.store.Mark(c.kind)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for offset := 0; ; {
+		page, err := c.list(ctx, s.opts.PageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, r := range page {
+			seen[r.key] = true
+			if err := s.apply(c.kind, r, changes); err != nil {
+				return err
+			}
+			if mark.before(r.updatedAt, r.id) {
+				mark.UpdatedAt, mark.ID = r.updatedAt, r.id
+			}
+		}
+		offset += len(page)
+		if len(page) < s.opts.PageSize {
+			mark.Offset = offset
+			break
+		}
+	}
+
+	err = s.store.Each(c.kind, func(key string, _ []byte) error {
+		if seen[key] {
+			return nil
+		}
+		changes.Deleted = append(changes.Deleted, key)
+		return s.store.Delete(c.kind, key)
+	})
+	if err != nil {
+		return err
+	}
+	mark.Synced = s.now()
+	return s.store.SetMark(c.kind, mark)
+}
+
+// apply stores r if it is new or differs from the stored copy.
+func (s *Syncer) apply(kind Kind, r record, changes *Changes) error {
+	old, err := s.store.Get(kind, r.key)
+	switch {
+	case errors.Is(err, coinjar.ErrNotFound):
+		changes.New = append(changes.New, r.key)
+	case err != nil:
+		return err
+	case bytes.Equal(old, r.data):
+		return nil
+	default:
+		changes.Changed = append(changes.Changed, r.key)
+	}
+	return s.store.Put(kind, r.key, r.data)
+}
+
+func (s *Syncer) transactions() collection {
+	convert := func(t *coinjar.Transaction) (record, error) {
+		return newRecord(t.UUID, t.UpdatedAt, t.ID, t)
+	}
+	return collection{
+		kind: Transactions,
+		list: func(ctx context.Context, limit, offset int) ([]record, error) {
+			page, err := s.client.ListTransactionsContext(ctx, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]record, len(page))
+			for i := range page {
+				if records[i], err = convert(&page[i]); err != nil {
+					return nil, err
+				}
+			}
+			return records, nil
+		},
+		get: func(ctx context.Context, key string) (record, error) {
+			t, err := s.client.TransactionContext(ctx, key)
+			if err != nil {
+				return record{}, err
+			}
+			return convert(t)
+		},
+		open: func(data []byte) bool {
+			var t coinjar.Transaction
+			return json.Unmarshal(data, &t) == nil && !t.Status.IsFinal()
+		},
+	}
+}
+
+func (s *Syncer) payments() collection {
+	convert := func(p *coinjar.Payment) (record, error) {
+		return newRecord(p.UUID, p.UpdatedAt, 0, p)
+	}
+	return collection{
+		kind: Payments,
+		list: func(ctx context.Context, limit, offset int) ([]record, error) {
+			page, err := s.client.ListPaymentsContext(ctx, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]record, len(page))
+			for i := range page {
+				if records[i], err = convert(&page[i]); err != nil {
+					return nil, err
+				}
+			}
+			return records, nil
+		},
+		get: func(ctx context.Context, key string) (record, error) {
+			p, err := s.client.PaymentContext(ctx, key)
+			if err != nil {
+				return record{}, err
+			}
+			return convert(p)
+		},
+		open: func(data []byte) bool {
+			var p coinjar.Payment
+			return json.Unmarshal(data, &p) == nil && !p.Status.IsFinal()
+		},
+	}
+}
+
+func (s *Syncer) contacts() collection {
+	return collection{
+		kind: Contacts,
+		list: func(ctx context.Context, limit, offset int) ([]record, error) {
+			page, err := s.client.ListContactsContext(ctx, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]record, len(page))
+			for i, c := range page {
+				if records[i], err = newRecord(c.UUID, c.UpdatedAt, 0, c); err != nil {
+					return nil, err
+				}
+			}
+			return records, nil
+		},
+	}
+}
+
+func (s *Syncer) bitcoinAddresses() collection {
+	return collection{
+		kind: BitcoinAddresses,
+		list: func(ctx context.Context, limit, offset int) ([]record, error) {
+			page, err := s.client.ListBitcoinAddressesContext(ctx, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]record, len(page))
+			for i, a := range page {
+				if records[i], err = newRecord(a.Address, coinjar.Timestamp{}, 0, a); err != nil {
+					return nil, err
+				}
+			}
+			return records, nil
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package coinjarsync
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+// requestLog counts the requests a client makes, by path.
+type requestLog struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (l *requestLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	l.paths = append(l.paths, req.URL.Path)
+	l.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (l *requestLog) reset() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.paths)
+	l.paths = nil
+	return n
+}
+
+func TestSyncIncremental(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	for i := 0; i < 25; i++ {
+		fake.AddTransaction(coinjar.Transaction{Amount: coinjar.AmountFromSatoshis(int64(i + 1)), Status: coinjar.TransactionReceived})
+	}
+	pending := fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("1.0"), Status: coinjar.TransactionUnconfirmed})
+	contact := fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	fake.AddBitcoinAddress(coinjar.BitcoinAddress{Label: "Donations"})
+
+	log := new(requestLog)
+	client := fake.Client(coinjar.WithTransport(log))
+	store := NewMemoryStore()
+	syncer := New(client, store, &Options{PageSize: 10})
+	ctx := context.Background()
+
+	result, err := syncer.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(result.Transactions.New), 26)
+	assertEqual(t, len(result.Contacts.New), 1)
+	assertEqual(t, len(result.BitcoinAddresses.New), 1)
+	log.reset()
+
+	// Nothing changed: only the pending transaction is refetched, and the
+	// transaction walk resumes past everything already seen.
+	result, err = syncer.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, result.Transactions.Empty(), true)
+	assertEqual(t, result.Contacts.Empty(), true)
+	assertEqual(t, log.reset(), 5)
+
+	pending.Status = coinjar.TransactionReceived
+	pending.Confirmations = 6
+	fake.UpdateTransaction(pending)
+	added := fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("0.1"), Status: coinjar.TransactionReceived})
+	fake.AddContact(coinjar.Contact{Name: "Jerrold", PayeeName: "jerrold@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	assertNil(t, client.DeleteContact(contact.UUID))
+
+	result, err = syncer.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(result.Transactions.Changed), 1)
+	assertEqual(t, result.Transactions.Changed[0], pending.UUID)
+	assertEqual(t, len(result.Transactions.New), 1)
+	assertEqual(t, result.Transactions.New[0], added.UUID)
+	assertEqual(t, len(result.Contacts.New), 1)
+	assertEqual(t, len(result.Contacts.Deleted), 1)
+	assertEqual(t, result.Contacts.Deleted[0], contact.UUID)
+
+	mark, err := store.Mark(Transactions)
+	assertNil(t, err)
+	assertEqual(t, mark.Offset, 27)
+	assertEqual(t, mark.ID, added.ID)
+}
+
+func TestSyncNewestFirst(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	for i := 0; i < 25; i++ {
+		fake.AddTransaction(coinjar.Transaction{Amount: coinjar.AmountFromSatoshis(int64(i + 1)), Status: coinjar.TransactionReceived})
+	}
+
+	log := new(requestLog)
+	syncer := New(fake.Client(coinjar.WithTransport(log)), NewMemoryStore(), &Options{PageSize: 10, NewestFirst: true})
+	_, err := syncer.Sync(context.Background())
+	assertNil(t, err)
+	log.reset()
+
+	// The fake lists oldest first, so the first page is already stale.
+	result, err := syncer.Sync(context.Background())
+	assertNil(t, err)
+	assertEqual(t, result.Transactions.Empty(), true)
+	assertEqual(t, log.reset(), 4)
+}
+
+func TestSyncError(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+
+	client := coinjar.New("wrongkey", coinjar.WithEndpoint(fake.URL))
+	store := NewMemoryStore()
+	_, err := New(client, store, nil).Sync(context.Background())
+	assertEqual(t, errors.Is(err, coinjar.ErrUnauthorized), true)
+	mark, _ := store.Mark(Transactions)
+	assertEqual(t, mark.Synced.IsZero(), true)
+}
+
+func TestQueries(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	first := fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("0.5"), Status: coinjar.TransactionReceived})
+	fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("-0.2"), Status: coinjar.TransactionSent})
+	fake.AddPayment(coinjar.Payment{Amount: coinjar.MustParseAmount("0.2"), PayeeName: "ryan@coinjar.io"})
+
+	store := NewMemoryStore()
+	_, err := New(fake.Client(), store, nil).Sync(context.Background())
+	assertNil(t, err)
+
+	received, err := QueryTransactions(store, func(t coinjar.Transaction) bool { return t.Amount.Sign() > 0 })
+	assertNil(t, err)
+	assertEqual(t, len(received), 1)
+	assertEqual(t, received[0].UUID, first.UUID)
+
+	all, err := QueryTransactions(store, nil)
+	assertNil(t, err)
+	assertEqual(t, len(all), 2)
+	assertEqual(t, all[0].UUID, first.UUID)
+
+	payments, err := QueryPayments(store, nil)
+	assertNil(t, err)
+	assertEqual(t, len(payments), 1)
+	assertEqual(t, payments[0].Status, coinjar.PaymentPending)
+
+	var transaction coinjar.Transaction
+	assertNil(t, Lookup(store, Transactions, first.UUID, &transaction))
+	assertEqual(t, transaction.Amount.String(), "0.5")
+	assertEqual(t, errors.Is(Lookup(store, Transactions, "missing", &transaction), coinjar.ErrNotFound), true)
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'nil' at %v:%v failed\n\tActual: %v", file, line, actual)
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
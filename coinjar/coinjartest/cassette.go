@@ -0,0 +1,247 @@
+package coinjartest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNoInteraction is returned, wrapped, by a replaying Recorder when a
+// request does not match any unplayed interaction in its cassette.
+var ErrNoInteraction = errors.New("coinjartest: no recorded interaction")
+
+const Redacted = "REDACTED"
+
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the
+	// network.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests through the real transport and appends every
+	// exchange to the cassette.
+	ModeRecord
+)
+
+// Cassette is the on-disk form of a recording.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest holds the parts of a request that replays are matched on.
+// Headers, including Authorization, are not kept, and any other occurrence of
+// the API key is replaced with Redacted, so the key never reaches the disk.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as the server sent it, except that the values
+// of credential headers such as Set-Cookie are replaced with Redacted.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records exchanges to a cassette file
+// or replays them from it. Plug it into a client with coinjar.WithTransport.
+//
+//	rec, err := coinjartest.NewRecorder("testdata/account.json", coinjartest.ModeReplay, nil)
+//	client := coinjar.New(key, coinjar.WithTransport(rec))
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	played   []bool
+}
+
+// NewRecorder opens the cassette at path. In ModeReplay the file must exist.
+// In ModeRecord requests go through transport, or http.DefaultTransport if it
+// is nil, and Save writes the cassette out.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, transport: transport}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+	if mode == ModeReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("coinjartest: cassette %s: %v", path, err)
+		}
+		r.played = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	redact := func(s string) string { return s }
+	if key, _, ok := req.BasicAuth(); ok && key != "" {
+		redact = func(s string) string { return strings.Replace(s, key, Redacted, -1) }
+	}
+	recorded, err := recordRequest(req, redact)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, recorded, redact)
+	}
+	return r.replay(req, recorded)
+}
+
+func recordRequest(req *http.Request, redact func(string) string) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   redact(req.URL.Path),
+		Query:  redact(req.URL.Query().Encode()),
+	}
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return recorded, err
+		}
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return recorded, err
+		}
+		recorded.Body = redact(string(data))
+	}
+	return recorded, nil
+}
+
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest, redact func(string) string) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header, redact),
+			Body:       redact(string(body)),
+		},
+	})
+	r.played = append(r.played, true)
+	return resp, nil
+}
+
+// sensitiveHeaders carry credentials, whether sent by the client or echoed
+// back by the server, so their values are never recorded.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+func redactHeader(h http.Header, redact func(string) string) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			out.Add(name, redact(v))
+		}
+	}
+	for _, name := range sensitiveHeaders {
+		if _, ok := out[name]; ok {
+			out.Set(name, Redacted)
+		}
+	}
+	return out
+}
+
+// replay serves the first unplayed interaction whose method, path, query and
+// body all match the request.
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.played[i] || in.Request != recorded {
+			continue
+		}
+		r.played[i] = true
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(in.Response.Body))),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s in %s (%d interactions, %d unplayed)",
+		ErrNoInteraction, describe(recorded), r.path, len(r.cassette.Interactions), len(r.unplayed()))
+}
+
+// Unplayed lists the interactions a replay has not served yet, to catch
+// requests a test expected but the code never made.
+func (r *Recorder) Unplayed() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unplayed()
+}
+
+func (r *Recorder) unplayed() (out []Interaction) {
+	for i, in := range r.cassette.Interactions {
+		if !r.played[i] {
+			out = append(out, in)
+		}
+	}
+	return
+}
+
+// Save writes the recorded cassette to its path. It does nothing in
+// ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+func describe(req RecordedRequest) string {
+	s := req.Method + " " + req.Path
+	if req.Query != "" {
+		s += "?" + req.Query
+	}
+	if req.Body != "" {
+		s += " with body " + req.Body
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package coinjartest
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	fake := NewServer("secretapikey")
+	fake.SetAccount(coinjar.User{Email: "test@example.com", AvailableBalance: coinjar.MustParseAmount("1.0")})
+	fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	dir, err := ioutil.TempDir("", "coinjartest")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+
+	rec, err := NewRecorder(path, ModeRecord, nil)
+	assertNil(t, err)
+	client := coinjar.New("secretapikey", coinjar.WithEndpoint(fake.URL), coinjar.WithTransport(rec))
+	_, err = client.Account()
+	assertNil(t, err)
+	_, err = client.ListContacts(10, 0)
+	assertNil(t, err)
+	_, err = client.CreateBitcoinAddress("label secretapikey")
+	assertNil(t, err)
+	_, err = client.Payment("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	assertNil(t, rec.Save())
+	fake.Close()
+
+	data, err := ioutil.ReadFile(path)
+	assertNil(t, err)
+	assertEqual(t, strings.Contains(string(data), "secretapikey"), false)
+	assertEqual(t, strings.Contains(string(data), "label+REDACTED"), true)
+
+	rec, err = NewRecorder(path, ModeReplay, nil)
+	assertNil(t, err)
+	client = coinjar.New("secretapikey", coinjar.WithEndpoint(fake.URL), coinjar.WithTransport(rec))
+
+	user, err := client.Account()
+	assertNil(t, err)
+	assertEqual(t, user.Email, "test@example.com")
+	assertEqual(t, len(rec.Unplayed()), 3)
+
+	contacts, err := client.ListContacts(10, 0)
+	assertNil(t, err)
+	assertEqual(t, contacts[0].Name, "Ryan Zhou")
+
+	_, err = client.ListContacts(20, 0)
+	assertEqual(t, errors.Is(err, ErrNoInteraction), true)
+	assertEqual(t, strings.Contains(err.Error(), "GET /contacts.json?limit=20&offset=0 in "+path+" (4 interactions, 2 unplayed)"), true)
+
+	_, err = client.CreateBitcoinAddress("label secretapikey")
+	assertNil(t, err)
+	_, err = client.Payment("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	assertEqual(t, len(rec.Unplayed()), 0)
+
+	_, err = client.Account()
+	assertEqual(t, errors.Is(err, ErrNoInteraction), true)
+}
+
+func TestRecordRedactsHeaders(t *testing.T) {
+	// A misbehaving server that echoes the credentials back.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("Set-Cookie", "session=secretsession")
+		w.Header().Set("X-Api-Key", "secretapikey")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user":{"email":"test@example.com"}}`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "coinjartest")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+
+	rec, err := NewRecorder(path, ModeRecord, nil)
+	assertNil(t, err)
+	client := coinjar.New("secretapikey", coinjar.WithEndpoint(server.URL), coinjar.WithTransport(rec))
+	_, err = client.Account()
+	assertNil(t, err)
+	assertNil(t, rec.Save())
+
+	data, err := ioutil.ReadFile(path)
+	assertNil(t, err)
+	for _, secret := range []string{"secretapikey", base64.StdEncoding.EncodeToString([]byte("secretapikey:")), "secretsession"} {
+		assertEqual(t, strings.Contains(string(data), secret), false)
+	}
+
+	rec, err = NewRecorder(path, ModeReplay, nil)
+	assertNil(t, err)
+	header := rec.cassette.Interactions[0].Response.Header
+	assertEqual(t, header.Get("Authorization"), Redacted)
+	assertEqual(t, header.Get("Set-Cookie"), Redacted)
+	assertEqual(t, header.Get("X-Api-Key"), Redacted)
+	assertEqual(t, header.Get("Content-Type"), "application/json")
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coinjartest")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	_, err = NewRecorder(filepath.Join(dir, "missing.json"), ModeReplay, nil)
+	assertEqual(t, err != nil, true)
+}
@@ -0,0 +1,625 @@
+// Package coinjartest provides an in-memory fake of the CoinJar API for
+// tests. It keeps state between requests, so writes made through a client
+// are visible to later reads.
+//
+//	fake := coinjartest.NewServer("someapikey")
+//	defer fake.Close()
+//	fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+//	client := coinjar.NewCustomClient("someapikey", fake.URL)
+package coinjartest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// Server is a fake CoinJar API listening on URL. It is safe for concurrent
+// use.
+type Server struct {
+	URL string
+
+	server *httptest.Server
+	apiKey string
+
+	mu           sync.Mutex
+	account      coinjar.User
+	addresses    []coinjar.BitcoinAddress
+	contacts     []coinjar.Contact
+	payments     []coinjar.Payment
+	transactions []coinjar.Transaction
+	orders       []coinjar.Order
+	rates        map[string]coinjar.FairRate
+	nextID       int
+}
+
+// NewServer starts a fake that accepts requests authenticated with apiKey.
+func NewServer(apiKey string) *Server {
+	s := &Server{
+		apiKey: apiKey,
+		rates:  make(map[string]coinjar.FairRate),
+		nextID: 10000,
+	}
+	s.account.UUID = newUUID()
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns a client for the fake, authenticated with its API key.
+func (s *Server) Client(opts ...coinjar.Option) *coinjar.Client {
+	return coinjar.New(s.apiKey, append([]coinjar.Option{coinjar.WithEndpoint(s.URL)}, opts...)...)
+}
+
+func (s *Server) SetAccount(u coinjar.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account = u
+}
+
+func (s *Server) Account() coinjar.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.account
+}
+
+// AddBitcoinAddress seeds an address. An empty Address is generated.
+func (s *Server) AddBitcoinAddress(a coinjar.BitcoinAddress) coinjar.BitcoinAddress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.Address == "" {
+		a.Address = newAddress()
+	}
+	s.addresses = append(s.addresses, a)
+	return a
+}
+
+// AddContact seeds a contact. An empty UUID or timestamp is generated.
+func (s *Server) AddContact(c coinjar.Contact) coinjar.Contact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.UUID == "" {
+		c.UUID = newUUID()
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = s.timestamp()
+	}
+	if c.UpdatedAt.IsZero() {
+		c.UpdatedAt = c.CreatedAt
+	}
+	s.contacts = append(s.contacts, c)
+	return c
+}
+
+// AddPayment seeds a payment. An empty UUID, status or timestamp is
+// generated.
+func (s *Server) AddPayment(p coinjar.Payment) coinjar.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.UUID == "" {
+		p.UUID = newUUID()
+	}
+	if p.Status == "" {
+		p.Status = coinjar.PaymentPending
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = s.timestamp()
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = p.CreatedAt
+	}
+	s.payments = append(s.payments, p)
+	return p
+}
+
+// AddTransaction seeds a transaction. An empty UUID, ID, status or timestamp
+// is generated.
+func (s *Server) AddTransaction(t coinjar.Transaction) coinjar.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addTransaction(&t)
+	return t
+}
+
+func (s *Server) addTransaction(t *coinjar.Transaction) {
+	if t.UUID == "" {
+		t.UUID = newUUID()
+	}
+	if t.ID == 0 {
+		s.nextID++
+		t.ID = s.nextID
+	}
+	if t.Status == "" {
+		t.Status = coinjar.TransactionPending
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = s.timestamp()
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = t.CreatedAt
+	}
+	s.transactions = append(s.transactions, *t)
+}
+
+// UpdateTransaction replaces the stored transaction with the same UUID, as if
+// the API had changed it. It reports whether the transaction was found.
+func (s *Server) UpdateTransaction(t coinjar.Transaction) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.transactions {
+		if s.transactions[i].UUID == t.UUID {
+			s.transactions[i] = t
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateBitcoinAddress replaces the stored address with the same Address, as
+// if it had received bitcoin. It reports whether the address was found.
+func (s *Server) UpdateBitcoinAddress(a coinjar.BitcoinAddress) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.addresses {
+		if s.addresses[i].Address == a.Address {
+			s.addresses[i] = a
+			return true
+		}
+	}
+	return false
+}
+
+// AddOrder seeds a Checkout order. An empty UUID, status, bitcoin address or
+// timestamp is generated.
+func (s *Server) AddOrder(o coinjar.Order) coinjar.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o.UUID == "" {
+		o.UUID = newUUID()
+	}
+	if o.Status == "" {
+		o.Status = coinjar.OrderUnpaid
+	}
+	if o.BitcoinAddress == "" {
+		o.BitcoinAddress = newAddress()
+	}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = s.timestamp()
+	}
+	if o.UpdatedAt.IsZero() {
+		o.UpdatedAt = o.CreatedAt
+	}
+	if o.ExpiresAt.IsZero() {
+		o.ExpiresAt = coinjar.Timestamp{Time: o.CreatedAt.Add(orderLifetime)}
+	}
+	s.orders = append(s.orders, o)
+	return o
+}
+
+func (s *Server) SetFairRate(currency string, r coinjar.FairRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[strings.ToUpper(currency)] = r
+}
+
+func (s *Server) BitcoinAddresses() []coinjar.BitcoinAddress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]coinjar.BitcoinAddress(nil), s.addresses...)
+}
+
+func (s *Server) Contacts() []coinjar.Contact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]coinjar.Contact(nil), s.contacts...)
+}
+
+func (s *Server) Payments() []coinjar.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]coinjar.Payment(nil), s.payments...)
+}
+
+func (s *Server) Orders() []coinjar.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]coinjar.Order(nil), s.orders...)
+}
+
+func (s *Server) Transactions() []coinjar.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]coinjar.Transaction(nil), s.transactions...)
+}
+
+func (s *Server) timestamp() coinjar.Timestamp {
+	return coinjar.Timestamp{Time: time.Now().Round(time.Millisecond)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if key, _, ok := r.BasicAuth(); !ok || key != s.apiKey {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.HasSuffix(path, ".json") {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".json"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	route := r.Method + " " + parts[0]
+	switch {
+	case route == "GET account" && len(parts) == 1:
+		writeJSON(w, map[string]interface{}{"user": s.account})
+
+	case route == "GET bitcoin_addresses" && len(parts) == 1:
+		lo, hi := page(r, len(s.addresses))
+		writeJSON(w, map[string]interface{}{"bitcoin_addresses": append([]coinjar.BitcoinAddress{}, s.addresses[lo:hi]...)})
+	case route == "POST bitcoin_addresses" && len(parts) == 1:
+		a := coinjar.BitcoinAddress{Label: r.FormValue("bitcoin_address[label]"), Address: newAddress()}
+		s.addresses = append(s.addresses, a)
+		writeJSON(w, map[string]interface{}{"bitcoin_address": a})
+	case route == "GET bitcoin_addresses" && len(parts) == 2:
+		s.withAddress(w, parts[1], func(a *coinjar.BitcoinAddress) {
+			writeJSON(w, map[string]interface{}{"bitcoin_address": a})
+		})
+	case route == "PUT bitcoin_addresses" && len(parts) == 2:
+		s.withAddress(w, parts[1], func(a *coinjar.BitcoinAddress) {
+			a.Label = r.FormValue("bitcoin_address[label]")
+			writeJSON(w, map[string]interface{}{"bitcoin_address": a})
+		})
+
+	case route == "GET contacts" && len(parts) == 1:
+		lo, hi := page(r, len(s.contacts))
+		writeJSON(w, map[string]interface{}{"contacts": append([]coinjar.Contact{}, s.contacts[lo:hi]...)})
+	case route == "POST contacts" && len(parts) == 1:
+		c := coinjar.Contact{UUID: newUUID(), CreatedAt: s.timestamp()}
+		if !s.fillContact(w, r, &c) {
+			return
+		}
+		s.contacts = append(s.contacts, c)
+		writeJSON(w, map[string]interface{}{"contact": c})
+	case route == "GET contacts" && len(parts) == 2:
+		s.withContact(w, parts[1], func(i int) {
+			writeJSON(w, map[string]interface{}{"contact": s.contacts[i]})
+		})
+	case route == "PUT contacts" && len(parts) == 2:
+		s.withContact(w, parts[1], func(i int) {
+			c := s.contacts[i]
+			if s.fillContact(w, r, &c) {
+				s.contacts[i] = c
+				writeJSON(w, map[string]interface{}{"contact": c})
+			}
+		})
+	case route == "DELETE contacts" && len(parts) == 2:
+		s.withContact(w, parts[1], func(i int) {
+			s.contacts = append(s.contacts[:i], s.contacts[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+	case route == "GET payments" && len(parts) == 1:
+		lo, hi := page(r, len(s.payments))
+		writeJSON(w, map[string]interface{}{"payments": append([]coinjar.Payment{}, s.payments[lo:hi]...)})
+	case route == "POST payments" && len(parts) == 1:
+		s.createPayment(w, r)
+	case route == "GET payments" && len(parts) == 2:
+		s.withPayment(w, parts[1], func(p *coinjar.Payment) {
+			writeJSON(w, map[string]interface{}{"payment": p})
+		})
+	case route == "PUT payments" && len(parts) == 3 && parts[2] == "confirm":
+		s.withPayment(w, parts[1], func(p *coinjar.Payment) { s.confirmPayment(w, p) })
+	case route == "PUT payments" && len(parts) == 3 && parts[2] == "cancel":
+		s.withPayment(w, parts[1], func(p *coinjar.Payment) { s.cancelPayment(w, p) })
+
+	case route == "GET transactions" && len(parts) == 1:
+		lo, hi := page(r, len(s.transactions))
+		writeJSON(w, map[string]interface{}{"transactions": append([]coinjar.Transaction{}, s.transactions[lo:hi]...)})
+	case route == "GET transactions" && len(parts) == 2:
+		for _, t := range s.transactions {
+			if t.UUID == parts[1] {
+				writeJSON(w, map[string]interface{}{"transaction": t})
+				return
+			}
+		}
+		// The real API answers a missing transaction with an error document
+		// and a 200 status.
+		writeJSON(w, map[string]string{"status": "404", "error": "Not Found"})
+
+	case route == "GET orders" && len(parts) == 1:
+		lo, hi := page(r, len(s.orders))
+		writeJSON(w, map[string]interface{}{"orders": append([]coinjar.Order{}, s.orders[lo:hi]...)})
+	case route == "POST orders" && len(parts) == 1:
+		s.createOrder(w, r)
+	case route == "GET orders" && len(parts) == 2:
+		for _, o := range s.orders {
+			if o.UUID == parts[1] {
+				writeJSON(w, map[string]interface{}{"order": o})
+				return
+			}
+		}
+		writeJSON(w, nil)
+
+	case route == "GET fair_rate" && len(parts) == 2:
+		rate, ok := s.rates[strings.ToUpper(parts[1])]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Unknown currency")
+			return
+		}
+		writeJSON(w, rate)
+
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// The real API answers a missing address, contact or payment with "null".
+func (s *Server) withAddress(w http.ResponseWriter, address string, fn func(*coinjar.BitcoinAddress)) {
+	for i := range s.addresses {
+		if s.addresses[i].Address == address {
+			fn(&s.addresses[i])
+			return
+		}
+	}
+	writeJSON(w, nil)
+}
+
+func (s *Server) withContact(w http.ResponseWriter, uuid string, fn func(int)) {
+	for i := range s.contacts {
+		if s.contacts[i].UUID == uuid {
+			fn(i)
+			return
+		}
+	}
+	writeJSON(w, nil)
+}
+
+func (s *Server) withPayment(w http.ResponseWriter, uuid string, fn func(*coinjar.Payment)) {
+	for i := range s.payments {
+		if s.payments[i].UUID == uuid {
+			fn(&s.payments[i])
+			return
+		}
+	}
+	writeJSON(w, nil)
+}
+
+func (s *Server) fillContact(w http.ResponseWriter, r *http.Request, c *coinjar.Contact) bool {
+	c.Name = r.FormValue("contact[name]")
+	c.PayeeName = r.FormValue("contact[payee_name]")
+	c.PayeeType = r.FormValue("contact[payee_type]")
+	if c.Name == "" || c.PayeeName == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Name and payee name are required")
+		return false
+	}
+	if c.PayeeType != coinjar.PayeeTypeWallet && c.PayeeType != coinjar.PayeeTypeBitcoinAddress {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid payee type")
+		return false
+	}
+	c.UpdatedAt = s.timestamp()
+	return true
+}
+
+func (s *Server) createPayment(w http.ResponseWriter, r *http.Request) {
+	amount, err := coinjar.ParseAmount(r.FormValue("payment[amount]"))
+	if err != nil || amount.Sign() <= 0 {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid amount")
+		return
+	}
+
+	p := coinjar.Payment{
+		UUID:      newUUID(),
+		Status:    coinjar.PaymentPending,
+		Amount:    amount,
+		Reference: r.FormValue("payment[reference]"),
+		CreatedAt: s.timestamp(),
+	}
+	p.UpdatedAt = p.CreatedAt
+	if uuid := r.FormValue("payment[contact_uuid]"); uuid != "" {
+		found := false
+		for _, c := range s.contacts {
+			if c.UUID == uuid {
+				p.PayeeName, p.PayeeType, found = c.PayeeName, c.PayeeType, true
+			}
+		}
+		if !found {
+			writeError(w, http.StatusUnprocessableEntity, "Unknown contact")
+			return
+		}
+	} else if name := r.FormValue("payment[payee_name]"); name != "" {
+		p.PayeeName = name
+		p.PayeeType = coinjar.PayeeTypeBitcoinAddress
+		if strings.Contains(name, "@") {
+			p.PayeeType = coinjar.PayeeTypeWallet
+		}
+	} else {
+		writeError(w, http.StatusUnprocessableEntity, "Payee is required")
+		return
+	}
+
+	s.payments = append(s.payments, p)
+	writeJSON(w, map[string]interface{}{"payment": p})
+}
+
+// confirmPayment completes a pending payment, debiting the account and
+// recording the outgoing transaction.
+func (s *Server) confirmPayment(w http.ResponseWriter, p *coinjar.Payment) {
+	if p.Status != coinjar.PaymentPending {
+		writeError(w, http.StatusUnprocessableEntity, "Payment is not pending")
+		return
+	}
+	if p.Amount.Cmp(s.account.AvailableBalance) > 0 {
+		writeError(w, http.StatusUnprocessableEntity, "Insufficient funds")
+		return
+	}
+
+	s.account.AvailableBalance = s.account.AvailableBalance.Sub(p.Amount)
+	t := coinjar.Transaction{
+		Amount:             p.Amount.Neg(),
+		Status:             coinjar.TransactionSent,
+		CounterpartyName:   p.PayeeName,
+		CounterpartyType:   "Sent to",
+		Reference:          p.Reference,
+		RelatedPaymentUUID: p.UUID,
+	}
+	s.addTransaction(&t)
+
+	p.Status = coinjar.PaymentCompleted
+	p.RelatedTransaction = &t
+	p.UpdatedAt = s.timestamp()
+	writeJSON(w, map[string]interface{}{"payment": p})
+}
+
+func (s *Server) cancelPayment(w http.ResponseWriter, p *coinjar.Payment) {
+	if p.Status != coinjar.PaymentPending {
+		writeError(w, http.StatusUnprocessableEntity, "Payment is not pending")
+		return
+	}
+	p.Status = coinjar.PaymentCancelled
+	p.UpdatedAt = s.timestamp()
+	writeJSON(w, map[string]interface{}{"payment": p})
+}
+
+// orderLifetime is how long a new order waits for payment.
+const orderLifetime = 15 * time.Minute
+
+// createOrder prices the order's items in bitcoin at the fair rate set for
+// its currency.
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	o := coinjar.Order{
+		UUID:              newUUID(),
+		Status:            coinjar.OrderUnpaid,
+		Currency:          strings.ToUpper(r.FormValue("order[currency]")),
+		BitcoinAddress:    newAddress(),
+		MerchantInvoice:   r.FormValue("order[merchant_invoice]"),
+		MerchantReference: r.FormValue("order[merchant_reference]"),
+		ReturnURL:         r.FormValue("order[return_url]"),
+		CancelURL:         r.FormValue("order[cancel_url]"),
+		NotifyURL:         r.FormValue("order[notify_url]"),
+		CreatedAt:         s.timestamp(),
+	}
+	for i := 0; ; i++ {
+		prefix := fmt.Sprintf("order[order_items_attributes][%d]", i)
+		name := r.FormValue(prefix + "[name]")
+		if name == "" {
+			break
+		}
+		quantity, err := strconv.Atoi(r.FormValue(prefix + "[quantity]"))
+		amount, err2 := coinjar.ParseAmount(r.FormValue(prefix + "[amount]"))
+		if err != nil || err2 != nil || quantity <= 0 || amount.Sign() <= 0 {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid order item")
+			return
+		}
+		o.Items = append(o.Items, coinjar.OrderItem{Name: name, Quantity: quantity, Amount: amount})
+		o.Amount = o.Amount.Add(amount.Mul(coinjar.AmountFromSatoshis(int64(quantity) * 1e8)))
+	}
+	if len(o.Items) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "Order items are required")
+		return
+	}
+	rate, ok := s.rates[o.Currency]
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Unknown currency")
+		return
+	}
+	btc, err := rate.ToBTC(o.Amount)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	o.BitcoinAmount = btc
+	o.UpdatedAt = o.CreatedAt
+	o.ExpiresAt = coinjar.Timestamp{Time: o.CreatedAt.Add(orderLifetime)}
+	s.orders = append(s.orders, o)
+	writeJSON(w, map[string]interface{}{"order": o})
+}
+
+// page returns the slice bounds selected by the limit and offset parameters.
+// Like the API, limit defaults to 100.
+func page(r *http.Request, n int) (lo, hi int) {
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit < 0 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(r.FormValue("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	lo, hi = offset, offset+limit
+	if lo > n {
+		lo = n
+	}
+	if hi > n {
+		hi = n
+	}
+	return
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": strconv.Itoa(status),
+		"error":  message,
+	})
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+const base58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// newAddress returns a random testnet pay-to-pubkey-hash address, base58check
+// encoded so that it passes the same checks as a real one.
+func newAddress() string {
+	payload := make([]byte, 1, 25)
+	payload[0] = 0x6f
+	var hash [20]byte
+	rand.Read(hash[:])
+	payload = append(payload, hash[:]...)
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	payload = append(payload, second[:4]...)
+
+	n := new(big.Int).SetBytes(payload)
+	radix := big.NewInt(int64(len(base58)))
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58[mod.Int64()])
+	}
+	// The version byte is never zero, so there are no leading '1's to add.
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
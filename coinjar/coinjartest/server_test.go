@@ -0,0 +1,270 @@
+package coinjartest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+func TestAccountAndAuth(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	fake.SetAccount(coinjar.User{
+		UUID:             "29d7f276-ba50-11e3-b016-7eddf9792095",
+		Email:            "test@example.com",
+		AvailableBalance: coinjar.MustParseAmount("1.0"),
+	})
+
+	user, err := coinjar.NewCustomClient("someapikey", fake.URL).Account()
+	assertNil(t, err)
+	assertEqual(t, user.Email, "test@example.com")
+	assertEqual(t, user.AvailableBalance.String(), "1.0")
+
+	_, err = coinjar.NewCustomClient("wrongkey", fake.URL).Account()
+	assertEqual(t, errors.Is(err, coinjar.ErrUnauthorized), true)
+}
+
+// TestWireFormat checks that the fake sends the API's snake_case keys, not Go
+// field names, which encoding/json would accept anyway when decoding.
+func TestWireFormat(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("0.5"), Confirmations: 3, Reference: "Order 42"})
+
+	req, err := http.NewRequest("GET", fake.URL+"/transactions.json", nil)
+	assertNil(t, err)
+	req.SetBasicAuth("someapikey", "")
+	resp, err := http.DefaultClient.Do(req)
+	assertNil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assertNil(t, err)
+
+	var wrapper struct {
+		Transactions []map[string]json.RawMessage `json:"transactions"`
+	}
+	assertNil(t, json.Unmarshal(body, &wrapper))
+	assertEqual(t, len(wrapper.Transactions), 1)
+	snakeCase := regexp.MustCompile(`^[a-z]+(_[a-z]+)*$`)
+	for k := range wrapper.Transactions[0] {
+		if !snakeCase.MatchString(k) {
+			t.Errorf("key %q is not snake_case", k)
+		}
+	}
+	for _, k := range []string{"amount", "confirmations", "id", "reference", "status", "uuid", "bitcoin_txid"} {
+		if _, ok := wrapper.Transactions[0][k]; !ok {
+			t.Errorf("key %q is missing", k)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	for i := 0; i < 250; i++ {
+		fake.AddTransaction(coinjar.Transaction{Amount: coinjar.AmountFromSatoshis(int64(i))})
+	}
+
+	client := fake.Client()
+	page, err := client.ListTransactions(10, 245)
+	assertNil(t, err)
+	assertEqual(t, len(page), 5)
+	assertEqual(t, page[0].Amount.Satoshis(), int64(245))
+
+	first, err := client.Transactions()
+	assertNil(t, err)
+	assertEqual(t, len(first), 100)
+
+	all, err := client.AllTransactions(context.Background(), nil)
+	assertNil(t, err)
+	assertEqual(t, len(all), 250)
+	assertEqual(t, all[249].Amount.Satoshis(), int64(249))
+
+	empty, err := client.ListContacts(100, 0)
+	assertNil(t, err)
+	assertEqual(t, len(empty), 0)
+}
+
+func TestNotFound(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	client := fake.Client()
+
+	_, err := client.Transaction("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	_, err = client.Contact("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	_, err = client.Payment("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	_, err = client.BitcoinAddress("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+	_, err = client.FairRate("XYZ")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+}
+
+func TestAddressWrites(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	client := fake.Client()
+
+	created, err := client.CreateBitcoinAddress("Invoice 42")
+	assertNil(t, err)
+	assertEqual(t, len(created.Address), 34)
+	uri, err := coinjar.ParsePaymentURI("bitcoin:" + created.Address)
+	assertNil(t, err)
+	assertEqual(t, uri.Address, created.Address)
+
+	updated, err := client.UpdateBitcoinAddressLabel(created.Address, "Invoice 43")
+	assertNil(t, err)
+	assertEqual(t, updated.Label, "Invoice 43")
+
+	fetched, err := client.BitcoinAddress(created.Address)
+	assertNil(t, err)
+	assertEqual(t, fetched.Label, "Invoice 43")
+	assertEqual(t, len(fake.BitcoinAddresses()), 1)
+}
+
+func TestContactWrites(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	seeded := fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	client := fake.Client()
+
+	contact, err := client.UpsertContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@example.com", PayeeType: coinjar.PayeeTypeWallet})
+	assertNil(t, err)
+	assertEqual(t, contact.UUID, seeded.UUID)
+	assertEqual(t, contact.PayeeName, "ryan@example.com")
+
+	created, err := client.CreateContact(coinjar.Contact{Name: "Jerrold", PayeeName: "jerrold@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	assertNil(t, err)
+	assertEqual(t, len(fake.Contacts()), 2)
+
+	assertNil(t, client.DeleteContact(created.UUID))
+	assertEqual(t, len(fake.Contacts()), 1)
+	assertEqual(t, errors.Is(client.DeleteContact(created.UUID), coinjar.ErrNotFound), true)
+}
+
+func TestPaymentLifecycle(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	fake.SetAccount(coinjar.User{AvailableBalance: coinjar.MustParseAmount("2.0")})
+	contact := fake.AddContact(coinjar.Contact{Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io", PayeeType: coinjar.PayeeTypeWallet})
+	client := fake.Client()
+
+	payment, err := client.CreatePayment(coinjar.PaymentRequest{
+		ContactUUID: contact.UUID,
+		Amount:      coinjar.MustParseAmount("1.25"),
+		Reference:   "Payout 1",
+	})
+	assertNil(t, err)
+	assertEqual(t, payment.Status, coinjar.PaymentPending)
+	assertEqual(t, payment.PayeeName, "ryan@coinjar.io")
+
+	payment, err = client.ConfirmPayment(payment.UUID)
+	assertNil(t, err)
+	assertEqual(t, payment.Status, coinjar.PaymentCompleted)
+	assertEqual(t, payment.RelatedTransaction.Amount.String(), "-1.25")
+	assertEqual(t, payment.RelatedTransaction.RelatedPaymentUUID, payment.UUID)
+
+	transaction, err := client.Transaction(payment.RelatedTransaction.UUID)
+	assertNil(t, err)
+	assertEqual(t, transaction.Status, coinjar.TransactionSent)
+
+	user, err := client.Account()
+	assertNil(t, err)
+	assertEqual(t, user.AvailableBalance.String(), "0.75")
+
+	second, err := client.CreatePayment(coinjar.PaymentRequest{
+		PayeeName: "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+		Amount:    coinjar.MustParseAmount("1.0"),
+	})
+	assertNil(t, err)
+	assertEqual(t, second.PayeeType, coinjar.PayeeTypeBitcoinAddress)
+
+	_, err = client.ConfirmPayment(second.UUID)
+	var apiErr *coinjar.APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, 422)
+	assertEqual(t, apiErr.Payload.Error, "Insufficient funds")
+
+	second, err = client.CancelPayment(second.UUID)
+	assertNil(t, err)
+	assertEqual(t, second.Status, coinjar.PaymentCancelled)
+	assertEqual(t, len(fake.Payments()), 2)
+}
+
+func TestFairRate(t *testing.T) {
+	fake := NewServer("someapikey")
+	defer fake.Close()
+	fake.SetFairRate("USD", coinjar.FairRate{
+		Bid:  coinjar.MustParseAmount("101.4713"),
+		Ask:  coinjar.MustParseAmount("103.5213"),
+		Spot: coinjar.MustParseAmount("102.4963"),
+	})
+
+	rate, err := fake.Client().FairRate("usd")
+	assertNil(t, err)
+	assertEqual(t, rate.Spot.String(), "102.4963")
+}
+
+func TestOrders(t *testing.T) {
+	fake := NewServer("merchantkey")
+	defer fake.Close()
+	fake.SetFairRate("AUD", coinjar.FairRate{Spot: coinjar.MustParseAmount("500")})
+	client := fake.Client()
+
+	order, err := client.CreateOrder(coinjar.OrderRequest{
+		Currency: "aud",
+		Items: []coinjar.OrderItem{
+			{Name: "T-shirt", Quantity: 2, Amount: coinjar.MustParseAmount("10")},
+			{Name: "Sticker", Quantity: 1, Amount: coinjar.MustParseAmount("5")},
+		},
+		NotifyURL: "https://shop.example.com/notify",
+	})
+	assertNil(t, err)
+	assertEqual(t, order.Status, coinjar.OrderUnpaid)
+	assertEqual(t, order.Amount.String(), "25.0")
+	assertEqual(t, order.BitcoinAmount.String(), "0.05")
+	assertEqual(t, len(order.BitcoinAddress), 34)
+	assertEqual(t, order.ExpiresAt.After(order.CreatedAt.Time), true)
+
+	fetched, err := client.Order(order.UUID)
+	assertNil(t, err)
+	assertEqual(t, fetched.NotifyURL, "https://shop.example.com/notify")
+	_, err = client.Order("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+
+	fake.AddOrder(coinjar.Order{Currency: "AUD", Status: coinjar.OrderCompleted})
+	orders, err := client.AllOrders(context.Background(), nil)
+	assertNil(t, err)
+	assertEqual(t, len(orders), 2)
+	assertEqual(t, orders[1].Status, coinjar.OrderCompleted)
+
+	_, err = client.CreateOrder(coinjar.OrderRequest{Currency: "XYZ", Items: order.Items})
+	var apiErr *coinjar.APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, 422)
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'nil' at %v:%v failed\n\tActual: %v", file, line, actual)
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
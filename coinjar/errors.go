@@ -0,0 +1,109 @@
+package coinjar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound     = errors.New("coinjar: not found")
+	ErrUnauthorized = errors.New("coinjar: unauthorized")
+	ErrRateLimited  = errors.New("coinjar: rate limited")
+)
+
+// ErrorPayload is the error document returned by the API, e.g.
+// {"status":"404","error":"Not Found"}.
+type ErrorPayload struct {
+	Status string
+	Error  string
+}
+
+func (p *ErrorPayload) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Status json.RawMessage
+		Error  string
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Status = strings.Trim(string(raw.Status), `"`)
+	if p.Status == "null" {
+		p.Status = ""
+	}
+	p.Error = raw.Error
+	return nil
+}
+
+// APIError is returned when the API responds with an error status, either
+// through the HTTP status line or inside the response body.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	Header     http.Header
+	Body       []byte
+	Payload    *ErrorPayload
+}
+
+func (e *APIError) Error() string {
+	msg := http.StatusText(e.StatusCode)
+	if e.Payload != nil && e.Payload.Error != "" {
+		msg = e.Payload.Error
+	}
+	return fmt.Sprintf("coinjar: %s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, msg)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// checkResponse turns an error response into an *APIError. Some endpoints
+// answer a missing resource with 200 and a "null" body, others with 200 and
+// an error document, so both are treated as errors as well.
+func checkResponse(resp *http.Response, method, endpoint string, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Endpoint:   endpoint,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if resp.StatusCode >= 400 {
+		payload := new(ErrorPayload)
+		if json.Unmarshal(trimmed, payload) == nil {
+			apiErr.Payload = payload
+		}
+		return apiErr
+	}
+
+	if string(trimmed) == "null" {
+		apiErr.StatusCode = http.StatusNotFound
+		return apiErr
+	}
+
+	payload := new(ErrorPayload)
+	if json.Unmarshal(trimmed, payload) != nil {
+		return nil
+	}
+	if code, err := strconv.Atoi(payload.Status); err == nil && code >= 400 {
+		apiErr.StatusCode = code
+		apiErr.Payload = payload
+		return apiErr
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package coinjar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNullBodyIsNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "null")
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+
+	contact, err := client.Contact("e359fd02-0079-4ef0-9f1f-706a84f39cca")
+	assertEqual(t, contact, (*Contact)(nil))
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+
+	payment, err := client.Payment("d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
+	assertEqual(t, payment, (*Payment)(nil))
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+
+	address, err := client.BitcoinAddress("mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
+	assertEqual(t, address, (*BitcoinAddress)(nil))
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+
+	var apiErr *APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, http.StatusNotFound)
+	assertEqual(t, apiErr.Method, "GET")
+	assertEqual(t, apiErr.Endpoint, "bitcoin_addresses/mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR.json")
+}
+
+func TestErrorDocumentIsNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"status":"404","error":"Not Found"}`)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	transaction, err := client.Transaction("3eb68998-8eb5-44a8-a115-49a383dcecfa")
+	assertEqual(t, transaction, (*Transaction)(nil))
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+	assertEqual(t, errors.Is(err, ErrUnauthorized), false)
+
+	var apiErr *APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, 404)
+	assertNotNil(t, apiErr.Payload)
+	assertEqual(t, apiErr.Payload.Status, "404")
+	assertEqual(t, apiErr.Payload.Error, "Not Found")
+	assertEqual(t, apiErr.Error(), "coinjar: GET transactions/3eb68998-8eb5-44a8-a115-49a383dcecfa.json: 404 Not Found")
+}
+
+func TestHTTPErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/account.json":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status":401,"error":"Invalid API key"}`)
+		case "/fair_rate/USD.json":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			t.Errorf("Requested unexpected endpoint: %v", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+
+	_, err := client.Account()
+	assertEqual(t, errors.Is(err, ErrUnauthorized), true)
+	var apiErr *APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.Payload.Status, "401")
+	assertEqual(t, apiErr.Error(), "coinjar: GET account.json: 401 Invalid API key")
+
+	_, err = client.FairRate("USD")
+	assertEqual(t, errors.Is(err, ErrRateLimited), true)
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, http.StatusTooManyRequests)
+	assertEqual(t, apiErr.Header.Get("Retry-After"), "30")
+	assertEqual(t, apiErr.Error(), "coinjar: GET fair_rate/USD.json: 429 Too Many Requests")
+}
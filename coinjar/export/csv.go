@@ -0,0 +1,95 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// Column is one CSV column: a header and how to format a transaction for it.
+type Column struct {
+	Name  string
+	Value func(coinjar.Transaction) string
+}
+
+var (
+	ColumnUUID                = Column{"uuid", func(t coinjar.Transaction) string { return t.UUID }}
+	ColumnID                  = Column{"id", func(t coinjar.Transaction) string { return strconv.Itoa(t.ID) }}
+	ColumnStatus              = Column{"status", func(t coinjar.Transaction) string { return string(t.Status) }}
+	ColumnAmount              = Column{"amount", func(t coinjar.Transaction) string { return t.Amount.String() }}
+	ColumnConfirmations       = Column{"confirmations", func(t coinjar.Transaction) string { return strconv.Itoa(t.Confirmations) }}
+	ColumnCounterpartyName    = Column{"counterparty_name", func(t coinjar.Transaction) string { return t.CounterpartyName }}
+	ColumnCounterpartyType    = Column{"counterparty_type", func(t coinjar.Transaction) string { return t.CounterpartyType }}
+	ColumnCounterpartyAddress = Column{"counterparty_address", func(t coinjar.Transaction) string { return t.CounterpartyAddress }}
+	ColumnBitcoinTxid         = Column{"bitcoin_txid", func(t coinjar.Transaction) string { return t.BitcoinTxid }}
+	ColumnReference           = Column{"reference", func(t coinjar.Transaction) string { return t.Reference }}
+	ColumnRelatedPaymentUUID  = Column{"related_payment_uuid", func(t coinjar.Transaction) string { return t.RelatedPaymentUUID }}
+	ColumnCreatedAt           = Column{"created_at", func(t coinjar.Transaction) string { return t.CreatedAt.String() }}
+	ColumnUpdatedAt           = Column{"updated_at", func(t coinjar.Transaction) string { return t.UpdatedAt.String() }}
+)
+
+// AllColumns lists every built-in column, in the order ColumnsByName
+// documents them.
+var AllColumns = []Column{
+	ColumnUUID, ColumnID, ColumnStatus, ColumnAmount, ColumnConfirmations,
+	ColumnCounterpartyName, ColumnCounterpartyType, ColumnCounterpartyAddress,
+	ColumnBitcoinTxid, ColumnReference, ColumnRelatedPaymentUUID,
+	ColumnCreatedAt, ColumnUpdatedAt,
+}
+
+// DefaultColumns is a compact set suited to reconciling a wallet.
+var DefaultColumns = []Column{
+	ColumnCreatedAt, ColumnUUID, ColumnStatus, ColumnAmount,
+	ColumnCounterpartyName, ColumnBitcoinTxid, ColumnReference,
+}
+
+// ColumnsByName looks up built-in columns by header name, e.g. from a
+// command-line flag.
+func ColumnsByName(names ...string) ([]Column, error) {
+	columns := make([]Column, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, c := range AllColumns {
+			if c.Name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("export: unknown column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// WriteCSV writes a header row and one row per transaction from src. It
+// returns the number of transactions written.
+func WriteCSV(w io.Writer, src Source, columns []Column) (n int, err error) {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		row[i] = c.Name
+	}
+	if err = cw.Write(row); err != nil {
+		return
+	}
+	for src.Next() {
+		t := src.Value()
+		for i, c := range columns {
+			row[i] = c.Value(t)
+		}
+		if err = cw.Write(row); err != nil {
+			return
+		}
+		n++
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return
+	}
+	return n, src.Err()
+}
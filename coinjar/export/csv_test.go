@@ -0,0 +1,37 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WriteCSV(&buf, SliceSource(sampleTransactions()), DefaultColumns)
+	assertNil(t, err)
+	assertEqual(t, n, 2)
+	assertEqual(t, buf.String(), "created_at,uuid,status,amount,counterparty_name,bitcoin_txid,reference\n"+
+		"2014-04-01T12:00:00.000+10:00,8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c,RECEIVED,0.5,\"Customer, Inc\",d5f7a0c1,Invoice <42>\n"+
+		"2014-04-01T12:00:00.000+10:00,1f3bd8e2-6c5e-4b5a-9a5d-7c0f3d6e9b21,SENT,-0.125,,,\n")
+}
+
+func TestColumnsByName(t *testing.T) {
+	columns, err := ColumnsByName("id", "amount", "counterparty_address")
+	assertNil(t, err)
+
+	var buf bytes.Buffer
+	_, err = WriteCSV(&buf, SliceSource(sampleTransactions()), columns)
+	assertNil(t, err)
+	assertEqual(t, buf.String(), "id,amount,counterparty_address\n1,0.5,\n2,-0.125,mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR\n")
+
+	_, err = ColumnsByName("amount", "colour")
+	assertEqual(t, err.Error(), `export: unknown column "colour"`)
+}
+
+func TestWriteCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WriteCSV(&buf, SliceSource(nil), []Column{ColumnUUID})
+	assertNil(t, err)
+	assertEqual(t, n, 0)
+	assertEqual(t, buf.String(), "uuid\n")
+}
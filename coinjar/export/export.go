@@ -0,0 +1,39 @@
+// Package export writes transaction history in formats accounting tools
+// read: CSV, JSON Lines and OFX bank statements.
+//
+// Every writer pulls transactions from a Source one at a time, so feeding it
+// a client iterator keeps only one page in memory however long the history:
+//
+//	it := client.IterTransactions(ctx, nil)
+//	n, err := export.WriteCSV(w, it, export.DefaultColumns)
+package export
+
+import "github.com/dteoh/coinjar-go/coinjar"
+
+// Source yields transactions. *coinjar.TransactionIterator satisfies it.
+type Source interface {
+	Next() bool
+	Value() coinjar.Transaction
+	Err() error
+}
+
+// SliceSource returns a Source over transactions already in memory.
+func SliceSource(transactions []coinjar.Transaction) Source {
+	return &sliceSource{transactions: transactions, i: -1}
+}
+
+type sliceSource struct {
+	transactions []coinjar.Transaction
+	i            int
+}
+
+func (s *sliceSource) Next() bool {
+	if s.i+1 >= len(s.transactions) {
+		return false
+	}
+	s.i++
+	return true
+}
+
+func (s *sliceSource) Value() coinjar.Transaction { return s.transactions[s.i] }
+func (s *sliceSource) Err() error                 { return nil }
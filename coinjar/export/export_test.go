@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+func sampleTransactions() []coinjar.Transaction {
+	created, _ := coinjar.ParseTimestamp("2014-04-01T12:00:00.000+10:00")
+	return []coinjar.Transaction{
+		{
+			UUID:             "8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c",
+			ID:               1,
+			Amount:           coinjar.MustParseAmount("0.5"),
+			Status:           coinjar.TransactionReceived,
+			CounterpartyName: "Customer, Inc",
+			BitcoinTxid:      "d5f7a0c1",
+			Reference:        "Invoice <42>",
+			CreatedAt:        created,
+			UpdatedAt:        created,
+		},
+		{
+			UUID:                "1f3bd8e2-6c5e-4b5a-9a5d-7c0f3d6e9b21",
+			ID:                  2,
+			Amount:              coinjar.MustParseAmount("-0.125"),
+			Status:              coinjar.TransactionSent,
+			CounterpartyAddress: "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+			CreatedAt:           created,
+			UpdatedAt:           created,
+		},
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WriteJSONLines(&buf, SliceSource(sampleTransactions()))
+	assertNil(t, err)
+	assertEqual(t, n, 2)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assertEqual(t, len(lines), 2)
+	var decoded coinjar.Transaction
+	assertNil(t, json.Unmarshal([]byte(lines[0]), &decoded))
+	assertEqual(t, decoded.Amount.String(), "0.5")
+	assertEqual(t, decoded.CounterpartyName, "Customer, Inc")
+	assertEqual(t, strings.Contains(lines[1], `"counterparty_address":"mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR"`), true)
+
+	var fields map[string]json.RawMessage
+	assertNil(t, json.Unmarshal([]byte(lines[0]), &fields))
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	assertEqual(t, strings.Join(keys, " "), "amount bitcoin_txid confirmations counterparty_address counterparty_name "+
+		"counterparty_type counterparty_user_id created_at id payment_id reference related_payment_uuid status "+
+		"updated_at user_id uuid")
+}
+
+func TestExportWalksEveryPage(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	for i := 0; i < 25; i++ {
+		fake.AddTransaction(coinjar.Transaction{Amount: coinjar.AmountFromSatoshis(int64(i + 1))})
+	}
+
+	it := fake.Client().IterTransactions(context.Background(), &coinjar.PageOptions{PageSize: 10})
+	var buf bytes.Buffer
+	n, err := WriteOFX(&buf, it, OFXOptions{AccountID: "wallet", End: time.Date(2014, 4, 2, 0, 0, 0, 0, time.UTC)})
+	assertNil(t, err)
+	assertEqual(t, n, 25)
+	assertEqual(t, strings.Count(buf.String(), "<STMTTRN>"), 25)
+	assertEqual(t, strings.Contains(buf.String(), "<BALAMT>0.00000325</BALAMT>"), true)
+}
+
+func TestExportSourceError(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+
+	client := coinjar.New("wrongkey", coinjar.WithEndpoint(fake.URL))
+	it := client.IterTransactions(context.Background(), nil)
+	_, err := WriteCSV(&bytes.Buffer{}, it, DefaultColumns)
+	assertEqual(t, err != nil, true)
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'nil' at %v:%v failed\n\tActual: %v", file, line, actual)
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
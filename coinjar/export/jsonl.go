@@ -0,0 +1,19 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WriteJSONLines writes each transaction from src as a JSON object on its own
+// line, using the API's field names. It returns the number written.
+func WriteJSONLines(w io.Writer, src Source) (n int, err error) {
+	enc := json.NewEncoder(w)
+	for src.Next() {
+		if err = enc.Encode(src.Value()); err != nil {
+			return
+		}
+		n++
+	}
+	return n, src.Err()
+}
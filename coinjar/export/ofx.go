@@ -0,0 +1,138 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// OFXOptions describes the statement that WriteOFX wraps around the
+// transactions.
+type OFXOptions struct {
+	// AccountID identifies the wallet, typically the user's UUID.
+	AccountID string
+	// Currency is the statement currency. Defaults to "XBT", the ISO 4217
+	// style code most accounting packages accept for bitcoin.
+	Currency string
+	// Start and End bound the statement period. They are written before the
+	// transactions, so they cannot be derived from a streamed Source.
+	// Defaults to the Unix epoch and the current time.
+	Start, End time.Time
+	// Balance is the ledger balance at End. When nil the sum of the exported
+	// amounts is used.
+	Balance *coinjar.Amount
+}
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// WriteOFX writes an OFX 2.2 bank statement with one STMTTRN per transaction
+// from src. It returns the number of transactions written.
+func WriteOFX(w io.Writer, src Source, opts OFXOptions) (n int, err error) {
+	if opts.Currency == "" {
+		opts.Currency = "XBT"
+	}
+	if opts.Start.IsZero() {
+		opts.Start = time.Unix(0, 0)
+	}
+	if opts.End.IsZero() {
+		opts.End = time.Now()
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(ofxHeader)
+	bw.WriteString("<OFX>\n")
+	bw.WriteString("<SIGNONMSGSRSV1><SONRS>")
+	bw.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	fmt.Fprintf(bw, "<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE>", ofxTime(opts.End))
+	bw.WriteString("</SONRS></SIGNONMSGSRSV1>\n")
+	bw.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID>")
+	bw.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	bw.WriteString("<STMTRS>")
+	ofxElement(bw, "CURDEF", opts.Currency)
+	bw.WriteString("<BANKACCTFROM><BANKID>COINJAR</BANKID>")
+	ofxElement(bw, "ACCTID", opts.AccountID)
+	bw.WriteString("<ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n")
+	fmt.Fprintf(bw, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxTime(opts.Start), ofxTime(opts.End))
+
+	var total coinjar.Amount
+	for src.Next() {
+		t := src.Value()
+		writeSTMTTRN(bw, t)
+		total = total.Add(t.Amount)
+		n++
+	}
+	if err = src.Err(); err != nil {
+		return
+	}
+
+	balance := total
+	if opts.Balance != nil {
+		balance = *opts.Balance
+	}
+	bw.WriteString("</BANKTRANLIST>\n")
+	fmt.Fprintf(bw, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", balance, ofxTime(opts.End))
+	bw.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n")
+	bw.WriteString("</OFX>\n")
+	return n, bw.Flush()
+}
+
+func writeSTMTTRN(w *bufio.Writer, t coinjar.Transaction) {
+	trnType := "CREDIT"
+	if t.Amount.Sign() < 0 {
+		trnType = "DEBIT"
+	}
+	posted := t.CreatedAt.Time
+	if posted.IsZero() {
+		posted = t.UpdatedAt.Time
+	}
+	fmt.Fprintf(w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>", trnType, ofxTime(posted), t.Amount)
+	ofxElement(w, "FITID", t.UUID)
+	if name := ofxName(t); name != "" {
+		ofxElement(w, "NAME", name)
+	}
+	if memo := ofxMemo(t); memo != "" {
+		ofxElement(w, "MEMO", memo)
+	}
+	w.WriteString("</STMTTRN>\n")
+}
+
+// ofxName is the payee shown by accounting packages. OFX limits NAME to 32
+// characters.
+func ofxName(t coinjar.Transaction) string {
+	name := t.CounterpartyName
+	if name == "" {
+		name = t.CounterpartyAddress
+	}
+	if r := []rune(name); len(r) > 32 {
+		name = string(r[:32])
+	}
+	return name
+}
+
+func ofxMemo(t coinjar.Transaction) string {
+	switch {
+	case t.Reference != "" && t.BitcoinTxid != "":
+		return t.Reference + " (" + t.BitcoinTxid + ")"
+	case t.Reference != "":
+		return t.Reference
+	default:
+		return t.BitcoinTxid
+	}
+}
+
+func ofxElement(w *bufio.Writer, name, value string) {
+	w.WriteString("<" + name + ">")
+	xml.EscapeText(w, []byte(value))
+	w.WriteString("</" + name + ">")
+}
+
+// ofxTime formats t in UTC as OFX's YYYYMMDDHHMMSS.XXX[gmt offset:tz name].
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+func TestWriteOFX(t *testing.T) {
+	balance := coinjar.MustParseAmount("1.25")
+	var buf bytes.Buffer
+	n, err := WriteOFX(&buf, SliceSource(sampleTransactions()), OFXOptions{
+		AccountID: "29d7f276-ba50-11e3-b016-7eddf9792095",
+		Start:     time.Date(2014, 4, 1, 0, 0, 0, 0, time.UTC),
+		End:       time.Date(2014, 4, 2, 0, 0, 0, 0, time.UTC),
+		Balance:   &balance,
+	})
+	assertNil(t, err)
+	assertEqual(t, n, 2)
+	out := buf.String()
+
+	assertEqual(t, strings.HasPrefix(out, `<?xml version="1.0"`), true)
+	assertEqual(t, strings.Contains(out, `<?OFX OFXHEADER="200" VERSION="220"`), true)
+	assertEqual(t, strings.Contains(out, "<CURDEF>XBT</CURDEF>"), true)
+	assertEqual(t, strings.Contains(out, "<ACCTID>29d7f276-ba50-11e3-b016-7eddf9792095</ACCTID>"), true)
+	assertEqual(t, strings.Contains(out, "<DTSTART>20140401000000.000[0:GMT]</DTSTART><DTEND>20140402000000.000[0:GMT]</DTEND>"), true)
+	assertEqual(t, strings.Contains(out, "<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20140401020000.000[0:GMT]</DTPOSTED><TRNAMT>0.5</TRNAMT>"+
+		"<FITID>8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c</FITID><NAME>Customer, Inc</NAME><MEMO>Invoice &lt;42&gt; (d5f7a0c1)</MEMO></STMTTRN>"), true)
+	assertEqual(t, strings.Contains(out, "<TRNTYPE>DEBIT</TRNTYPE>"), true)
+	assertEqual(t, strings.Contains(out, "<NAME>mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR</NAME>"), false)
+	assertEqual(t, strings.Contains(out, "<NAME>mgk4K3gdBKRDUJ27jB1VzAATH4upGquY</NAME>"), true)
+	assertEqual(t, strings.Contains(out, "<BALAMT>1.25</BALAMT>"), true)
+
+	// OFX 2.x is XML, so the whole document must be well formed.
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		assertNil(t, err)
+		if err != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Package invoice bills customers in bitcoin. Each invoice gets a bitcoin
+// address of its own and a BTC amount fixed from the fair rate when it is
+// created. Incoming transactions to that address move it through its states
+// until it is paid or expires.
+//
+//	invoices := invoice.New(client, invoice.NewMemoryStore(), nil)
+//	inv, err := invoices.Create(ctx, invoice.Request{Reference: "Order 42", Currency: "AUD", Amount: total})
+//	// Show inv.Address and inv.Amount to the customer, then later:
+//	changed, err := invoices.Sync(ctx)
+package invoice
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+type State string
+
+const (
+	Unpaid        State = "UNPAID"
+	PartiallyPaid State = "PARTIALLY_PAID"
+	Paid          State = "PAID"
+	Overpaid      State = "OVERPAID"
+	Expired       State = "EXPIRED"
+)
+
+// Open reports whether an invoice in this state is still waiting for money.
+func (s State) Open() bool {
+	return s == Unpaid || s == PartiallyPaid
+}
+
+// Invoice is a request for Amount bitcoin, to be paid to Address before
+// ExpiresAt.
+type Invoice struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	Address   string `json:"address"`
+	// Currency and Price are what the invoice was raised for. Rate is the
+	// fair rate Amount was fixed at, zero for invoices raised in BTC.
+	Currency string           `json:"currency"`
+	Price    coinjar.Amount   `json:"price"`
+	Rate     coinjar.FairRate `json:"rate"`
+	Amount   coinjar.Amount   `json:"amount"`
+	// Received totals the transactions in Transactions, by UUID.
+	Received     coinjar.Amount `json:"received"`
+	Transactions []string       `json:"transactions"`
+	State        State          `json:"state"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+}
+
+// Outstanding is what is left to pay, zero once the invoice is paid.
+func (inv *Invoice) Outstanding() coinjar.Amount {
+	if left := inv.Amount.Sub(inv.Received); left.Sign() > 0 {
+		return left
+	}
+	return coinjar.Amount{}
+}
+
+// PaymentURI returns a BIP21 URI asking for the outstanding amount, labelled
+// with the invoice reference.
+func (inv *Invoice) PaymentURI() coinjar.PaymentURI {
+	return coinjar.PaymentURI{Address: inv.Address, Amount: inv.Outstanding(), Label: inv.Reference}
+}
+
+func (inv *Invoice) hasTransaction(uuid string) bool {
+	for _, t := range inv.Transactions {
+		if t == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// settle sets the state from the amount received. Expired invoices stay
+// expired, so that late money is dealt with by hand.
+func (inv *Invoice) settle(now time.Time) {
+	if inv.State == Expired {
+		return
+	}
+	switch c := inv.Received.Cmp(inv.Amount); {
+	case inv.Received.Sign() <= 0:
+		inv.State = Unpaid
+	case c < 0:
+		inv.State = PartiallyPaid
+	case c == 0:
+		inv.State = Paid
+	default:
+		inv.State = Overpaid
+	}
+	if inv.State.Open() && !now.Before(inv.ExpiresAt) {
+		inv.State = Expired
+	}
+}
+
+const DefaultExpiry = 15 * time.Minute
+
+type Options struct {
+	// Expiry is how long an invoice waits for payment. Defaults to
+	// DefaultExpiry.
+	Expiry time.Duration
+	// LabelPrefix starts the label of each invoice's address, followed by
+	// the invoice reference. Defaults to "Invoice ".
+	LabelPrefix string
+	// MinConfirmations is the number of confirmations a transaction needs to
+	// count towards an invoice. Defaults to zero, counting unconfirmed
+	// transactions.
+	MinConfirmations int
+	// ScanLimit is the number of recent transactions Sync examines. Defaults
+	// to 200.
+	ScanLimit int
+	// NewestFirst says the API lists transactions newest first, as for
+	// coinjarsync.Options. Sync then reads ScanLimit from offset 0.
+	// Otherwise it pages from the start of the last window to the end of the
+	// list, so the first Sync reads every transaction.
+	NewestFirst bool
+	// Addresses returns the addresses a transaction may have been paid to,
+	// which Apply looks invoices up by. The API does not document a field for
+	// the receiving address of an incoming transaction, so this defaults to
+	// its CounterpartyAddress and CounterpartyName.
+	Addresses func(coinjar.Transaction) []string
+	// Pool, if set, supplies invoice addresses instead of creating one per
+	// invoice. Pooled addresses keep the pool's label. Each is retired from
+	// the pool as soon as it is given to an invoice, so a late payment to an
+	// expired invoice can never be credited to a newer one.
+	Pool *AddressPool
+}
+
+// Request describes an invoice to raise. Amount is in Currency, which may be
+// "BTC".
+type Request struct {
+	Reference string
+	Currency  string
+	Amount    coinjar.Amount
+}
+
+// Manager raises invoices and keeps their state in a Store. It is safe for
+// concurrent use, such as Sync running alongside Apply calls from a
+// WebhookHandler, within one process.
+type Manager struct {
+	client *coinjar.Client
+	store  Store
+	opts   Options
+	now    func() time.Time
+
+	// mu serializes the read-modify-write of stored invoices.
+	mu sync.Mutex
+	// offset is where the window Sync reads starts when transactions are
+	// listed oldest first.
+	offset int
+}
+
+// New returns a Manager. opts may be nil.
+func New(client *coinjar.Client, store Store, opts *Options) *Manager {
+	m := &Manager{client: client, store: store, now: time.Now}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Expiry <= 0 {
+		m.opts.Expiry = DefaultExpiry
+	}
+	if m.opts.LabelPrefix == "" {
+		m.opts.LabelPrefix = "Invoice "
+	}
+	if m.opts.ScanLimit <= 0 {
+		m.opts.ScanLimit = 200
+	}
+	if m.opts.Addresses == nil {
+		m.opts.Addresses = func(t coinjar.Transaction) []string {
+			return []string{t.CounterpartyAddress, t.CounterpartyName}
+		}
+	}
+	return m
+}
+
+// Create prices req in bitcoin at the current fair rate, creates an address
+// for it and stores the new invoice.
+func (m *Manager) Create(ctx context.Context, req Request) (*Invoice, error) {
+	if req.Amount.Sign() <= 0 {
+		return nil, errors.New("invoice: amount must be positive")
+	}
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		return nil, errors.New("invoice: currency is required")
+	}
+
+	inv := &Invoice{
+		ID:        newID(),
+		Reference: req.Reference,
+		Currency:  currency,
+		Price:     req.Amount,
+		Amount:    req.Amount,
+		State:     Unpaid,
+	}
+	if currency != "BTC" {
+		rate, err := m.client.FairRateContext(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		if inv.Amount, err = rate.ToBTC(req.Amount); err != nil {
+			return nil, err
+		}
+		inv.Rate = *rate
+	}
+
+	address, err := m.address(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	inv.Address = address
+	inv.CreatedAt = m.now()
+	inv.ExpiresAt = inv.CreatedAt.Add(m.opts.Expiry)
+
+	if err := m.store.Put(*inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (m *Manager) address(ctx context.Context, inv *Invoice) (string, error) {
+	if m.opts.Pool != nil {
+		a, err := m.opts.Pool.Acquire(ctx, inv.ID)
+		if err != nil {
+			return "", err
+		}
+		return a.Address, m.opts.Pool.Retire(a.Address)
+	}
+	label := m.opts.LabelPrefix + inv.Reference
+	if inv.Reference == "" {
+		label = m.opts.LabelPrefix + inv.ID
+	}
+	a, err := m.client.CreateBitcoinAddressContext(ctx, label)
+	if err != nil {
+		return "", err
+	}
+	return a.Address, nil
+}
+
+// Apply counts t towards the invoice for its address, if any, and stores the
+// result. Transactions are matched on Options.Addresses, and each is counted
+// once however often it is applied. It reports the updated invoice, or nil if
+// t is not for an invoice or did not change it.
+func (m *Manager) Apply(t coinjar.Transaction) (*Invoice, error) {
+	if t.Amount.Sign() <= 0 || t.Status == coinjar.TransactionCancelled || t.Status == coinjar.TransactionFailed {
+		return nil, nil
+	}
+	if t.Confirmations < m.opts.MinConfirmations {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var inv Invoice
+	var err error
+	for _, address := range m.opts.Addresses(t) {
+		if address == "" {
+			continue
+		}
+		if inv, err = m.store.ByAddress(address); !errors.Is(err, coinjar.ErrNotFound) {
+			break
+		}
+	}
+	if errors.Is(err, coinjar.ErrNotFound) || inv.ID == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if inv.hasTransaction(t.UUID) {
+		return nil, nil
+	}
+
+	inv.Transactions = append(inv.Transactions, t.UUID)
+	inv.Received = inv.Received.Add(t.Amount)
+	inv.settle(m.now())
+	if err := m.store.Put(inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// Sync applies the most recent transactions, at least Options.ScanLimit of
+// them, then expires open invoices past their expiry. It returns every
+// invoice that changed.
+func (m *Manager) Sync(ctx context.Context) ([]Invoice, error) {
+	transactions, err := m.recent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]Invoice)
+	for _, t := range transactions {
+		inv, err := m.Apply(t)
+		if err != nil {
+			return nil, err
+		}
+		if inv != nil {
+			changed[inv.ID] = *inv
+		}
+	}
+
+	expired, err := m.Expire()
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range expired {
+		changed[inv.ID] = inv
+	}
+
+	out := make([]Invoice, 0, len(changed))
+	for _, inv := range changed {
+		out = append(out, inv)
+	}
+	return out, nil
+}
+
+// recent fetches the transactions for Sync to apply. Oldest first, it reads
+// from the start of the last window to the end of the list and keeps the
+// newest ScanLimit as the next window.
+func (m *Manager) recent(ctx context.Context) ([]coinjar.Transaction, error) {
+	if m.opts.NewestFirst {
+		return m.client.AllTransactions(ctx, &coinjar.PageOptions{Max: m.opts.ScanLimit})
+	}
+	pageSize := m.opts.ScanLimit
+	if pageSize > coinjar.MaxPageSize {
+		pageSize = coinjar.MaxPageSize
+	}
+	m.mu.Lock()
+	start := m.offset
+	m.mu.Unlock()
+
+	var transactions []coinjar.Transaction
+	offset := start
+	for {
+		page, err := m.client.ListTransactionsContext(ctx, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, page...)
+		offset += len(page)
+		if len(page) < pageSize {
+			break
+		}
+	}
+	next := offset - m.opts.ScanLimit
+	if next < 0 {
+		next = 0
+	}
+	if len(transactions) == 0 && start > 0 {
+		// The list shrank past the window; start again from the top.
+		next = 0
+	}
+	m.mu.Lock()
+	m.offset = next
+	m.mu.Unlock()
+	return transactions, nil
+}
+
+// Expire moves open invoices past their expiry to Expired and returns them.
+func (m *Manager) Expire() ([]Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	invoices, err := m.store.List()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	var expired []Invoice
+	for _, inv := range invoices {
+		if !inv.State.Open() || now.Before(inv.ExpiresAt) {
+			continue
+		}
+		inv.State = Expired
+		if err := m.store.Put(inv); err != nil {
+			return nil, err
+		}
+		expired = append(expired, inv)
+	}
+	return expired, nil
+}
+
+// Get returns the stored invoice with id, or coinjar.ErrNotFound.
+func (m *Manager) Get(id string) (*Invoice, error) {
+	inv, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,265 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+func newManager(store Store) (*coinjartest.Server, *Manager, *time.Time) {
+	fake := coinjartest.NewServer("someapikey")
+	fake.SetFairRate("AUD", coinjar.FairRate{
+		Bid:  coinjar.MustParseAmount("99"),
+		Ask:  coinjar.MustParseAmount("101"),
+		Spot: coinjar.MustParseAmount("100"),
+	})
+	now := time.Date(2014, 4, 1, 12, 0, 0, 0, time.UTC)
+	m := New(fake.Client(), store, &Options{Expiry: time.Hour})
+	m.now = func() time.Time { return now }
+	return fake, m, &now
+}
+
+func TestCreate(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	ctx := context.Background()
+
+	inv, err := m.Create(ctx, Request{Reference: "Order 42", Currency: "aud", Amount: coinjar.MustParseAmount("25")})
+	assertNil(t, err)
+	assertEqual(t, inv.Currency, "AUD")
+	assertEqual(t, inv.Amount.String(), "0.25")
+	assertEqual(t, inv.Rate.Spot, coinjar.MustParseAmount("100"))
+	assertEqual(t, inv.State, Unpaid)
+	assertEqual(t, inv.ExpiresAt.Sub(inv.CreatedAt), time.Hour)
+
+	addresses := fake.BitcoinAddresses()
+	assertEqual(t, len(addresses), 1)
+	assertEqual(t, addresses[0].Address, inv.Address)
+	assertEqual(t, addresses[0].Label, "Invoice Order 42")
+	assertEqual(t, inv.PaymentURI().String(), "bitcoin:"+inv.Address+"?amount=0.25&label=Order%2042")
+
+	stored, err := m.Get(inv.ID)
+	assertNil(t, err)
+	assertEqual(t, stored.Address, inv.Address)
+
+	btc, err := m.Create(ctx, Request{Currency: "BTC", Amount: coinjar.MustParseAmount("0.1")})
+	assertNil(t, err)
+	assertEqual(t, btc.Amount, coinjar.MustParseAmount("0.1"))
+	assertEqual(t, fake.BitcoinAddresses()[1].Label, "Invoice "+btc.ID)
+
+	_, err = m.Create(ctx, Request{Currency: "XYZ", Amount: coinjar.MustParseAmount("1")})
+	assertEqual(t, err != nil, true)
+	_, err = m.Create(ctx, Request{Currency: "AUD"})
+	assertEqual(t, err != nil, true)
+	assertEqual(t, len(fake.BitcoinAddresses()), 2)
+}
+
+func TestSyncStates(t *testing.T) {
+	fake, m, now := newManager(NewMemoryStore())
+	defer fake.Close()
+	ctx := context.Background()
+
+	create := func() *Invoice {
+		inv, err := m.Create(ctx, Request{Currency: "AUD", Amount: coinjar.MustParseAmount("10")})
+		assertNil(t, err)
+		return inv
+	}
+	pay := func(inv *Invoice, amount string) {
+		fake.AddTransaction(coinjar.Transaction{
+			Amount:              coinjar.MustParseAmount(amount),
+			Status:              coinjar.TransactionUnconfirmed,
+			CounterpartyAddress: inv.Address,
+		})
+	}
+	state := func(inv *Invoice) State {
+		stored, err := m.Get(inv.ID)
+		assertNil(t, err)
+		return stored.State
+	}
+
+	partial, paid, over, unpaid := create(), create(), create(), create()
+	pay(partial, "0.04")
+	pay(paid, "0.06")
+	pay(paid, "0.04")
+	pay(over, "0.2")
+	fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("1"), CounterpartyAddress: "elsewhere"})
+
+	changed, err := m.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(changed), 3)
+	assertEqual(t, state(partial), PartiallyPaid)
+	assertEqual(t, state(paid), Paid)
+	assertEqual(t, state(over), Overpaid)
+	assertEqual(t, state(unpaid), Unpaid)
+
+	// Transactions already counted are not counted again.
+	changed, err = m.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(changed), 0)
+	stored, _ := m.Get(paid.ID)
+	assertEqual(t, stored.Received.String(), "0.1")
+	assertEqual(t, len(stored.Transactions), 2)
+	assertEqual(t, stored.Outstanding().IsZero(), true)
+
+	// Open invoices expire; settled ones stay settled, and late money is
+	// recorded without reopening an expired invoice.
+	*now = now.Add(time.Hour)
+	changed, err = m.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(changed), 2)
+	assertEqual(t, state(partial), Expired)
+	assertEqual(t, state(unpaid), Expired)
+	assertEqual(t, state(paid), Paid)
+
+	pay(partial, "0.06")
+	_, err = m.Sync(ctx)
+	assertNil(t, err)
+	stored, _ = m.Get(partial.ID)
+	assertEqual(t, stored.State, Expired)
+	assertEqual(t, stored.Received.String(), "0.1")
+}
+
+func TestApply(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	m.opts.MinConfirmations = 3
+
+	inv, err := m.Create(context.Background(), Request{Currency: "BTC", Amount: coinjar.MustParseAmount("1")})
+	assertNil(t, err)
+
+	tx := coinjar.Transaction{UUID: "tx-1", Amount: coinjar.MustParseAmount("1"), Confirmations: 1, CounterpartyName: inv.Address}
+	updated, err := m.Apply(tx)
+	assertNil(t, err)
+	assertEqual(t, updated == nil, true)
+
+	tx.Confirmations = 3
+	tx.Status = coinjar.TransactionFailed
+	updated, err = m.Apply(tx)
+	assertNil(t, err)
+	assertEqual(t, updated == nil, true)
+
+	tx.Status = coinjar.TransactionReceived
+	updated, err = m.Apply(tx)
+	assertNil(t, err)
+	assertEqual(t, updated.State, Paid)
+
+	updated, err = m.Apply(tx)
+	assertNil(t, err)
+	assertEqual(t, updated == nil, true)
+}
+
+func TestSyncReadsToTheEnd(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	m.opts.ScanLimit = 2
+	ctx := context.Background()
+
+	inv, err := m.Create(ctx, Request{Currency: "BTC", Amount: coinjar.MustParseAmount("1")})
+	assertNil(t, err)
+	for i := 0; i < 5; i++ {
+		fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("1"), CounterpartyAddress: "elsewhere"})
+	}
+	changed, err := m.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(changed), 0)
+
+	// The fake lists oldest first, so the payment lands past the first page.
+	fake.AddTransaction(coinjar.Transaction{Amount: coinjar.MustParseAmount("1"), CounterpartyAddress: inv.Address})
+	changed, err = m.Sync(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(changed), 1)
+	assertEqual(t, changed[0].State, Paid)
+	assertEqual(t, m.offset, 4)
+}
+
+func TestApplyConcurrent(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	inv, err := m.Create(context.Background(), Request{Currency: "BTC", Amount: coinjar.MustParseAmount("1")})
+	assertNil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := m.Apply(coinjar.Transaction{UUID: fmt.Sprint("tx-", i), Amount: coinjar.MustParseAmount("0.01"), CounterpartyAddress: inv.Address})
+			assertNil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	stored, _ := m.Get(inv.ID)
+	assertEqual(t, len(stored.Transactions), 20)
+	assertEqual(t, stored.Received.String(), "0.2")
+}
+
+func TestApplyAddresses(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	m.opts.Addresses = func(t coinjar.Transaction) []string { return []string{t.Reference} }
+	inv, err := m.Create(context.Background(), Request{Currency: "BTC", Amount: coinjar.MustParseAmount("1")})
+	assertNil(t, err)
+
+	updated, err := m.Apply(coinjar.Transaction{UUID: "tx-1", Amount: coinjar.MustParseAmount("1"), CounterpartyAddress: inv.Address})
+	assertNil(t, err)
+	assertEqual(t, updated == nil, true)
+	updated, err = m.Apply(coinjar.Transaction{UUID: "tx-2", Amount: coinjar.MustParseAmount("1"), Reference: inv.Address})
+	assertNil(t, err)
+	assertEqual(t, updated.State, Paid)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "invoice")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "invoices.json")
+	store, err := OpenFileStore(path)
+	assertNil(t, err)
+	fake, m, _ := newManager(store)
+	defer fake.Close()
+
+	inv, err := m.Create(context.Background(), Request{Reference: "Order 7", Currency: "AUD", Amount: coinjar.MustParseAmount("5")})
+	assertNil(t, err)
+	_, err = m.Apply(coinjar.Transaction{UUID: "tx-1", Amount: coinjar.MustParseAmount("0.05"), CounterpartyAddress: inv.Address})
+	assertNil(t, err)
+
+	reopened, err := OpenFileStore(path)
+	assertNil(t, err)
+	stored, err := reopened.ByAddress(inv.Address)
+	assertNil(t, err)
+	assertEqual(t, stored.ID, inv.ID)
+	assertEqual(t, stored.Reference, "Order 7")
+	assertEqual(t, stored.State, Paid)
+	assertEqual(t, stored.Received, coinjar.MustParseAmount("0.05"))
+	assertEqual(t, stored.Rate.Spot, coinjar.MustParseAmount("100"))
+	assertEqual(t, stored.ExpiresAt.Equal(inv.ExpiresAt), true)
+
+	_, err = reopened.Get("missing")
+	assertEqual(t, errors.Is(err, coinjar.ErrNotFound), true)
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'nil' at %v:%v failed\n\tActual: %v", file, line, actual)
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
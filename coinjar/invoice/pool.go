@@ -0,0 +1,351 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+type PoolState string
+
+const (
+	// AddressReady addresses are waiting to be handed out.
+	AddressReady PoolState = "READY"
+	// AddressAssigned addresses have been handed out and are waiting for
+	// money or their timeout.
+	AddressAssigned PoolState = "ASSIGNED"
+	// AddressUsed addresses received money, or were retired by their owner,
+	// and are never handed out again.
+	AddressUsed PoolState = "USED"
+)
+
+// PoolAddress is an address kept by an AddressPool. Owner is what the address
+// was handed out for, such as an invoice ID.
+type PoolAddress struct {
+	Address    string    `json:"address"`
+	Label      string    `json:"label"`
+	State      PoolState `json:"state"`
+	Owner      string    `json:"owner,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+const (
+	DefaultPoolSize    = 10
+	DefaultPoolTimeout = 24 * time.Hour
+)
+
+type PoolOptions struct {
+	// Size is the number of ready addresses Fill keeps. Defaults to
+	// DefaultPoolSize.
+	Size int
+	// Label is given to every address the pool creates, and picks out the
+	// addresses Adopt takes over. Defaults to "Address pool".
+	Label string
+	// Timeout is how long an address stays assigned before Recycle checks
+	// it. Addresses that have received nothing by then are handed out
+	// again. Defaults to DefaultPoolTimeout.
+	Timeout time.Duration
+	// Interval is how often Run recycles and refills. Defaults to
+	// coinjar.DefaultWatchInterval.
+	Interval time.Duration
+	// OnError is called with every error Run meets.
+	OnError func(error)
+}
+
+// AddressPool keeps addresses generated ahead of time, so handing one out
+// does not wait on the API. Every change is written to its PoolStore before
+// it takes effect, so assignments survive restarts. AddressPool is safe for
+// concurrent use.
+//
+//	pool := invoice.NewAddressPool(client, store, &invoice.PoolOptions{Size: 20})
+//	go pool.Run(ctx)
+//	a, err := pool.Acquire(ctx, "Order 42")
+type AddressPool struct {
+	client *coinjar.Client
+	store  PoolStore
+	opts   PoolOptions
+	now    func() time.Time
+
+	mu        sync.Mutex
+	loaded    bool
+	addresses map[string]PoolAddress
+	ready     []string
+
+	// fillMu keeps concurrent Fill calls from overfilling the pool.
+	fillMu sync.Mutex
+}
+
+// NewAddressPool returns a pool keeping its addresses in store. opts may be
+// nil.
+func NewAddressPool(client *coinjar.Client, store PoolStore, opts *PoolOptions) *AddressPool {
+	p := &AddressPool{client: client, store: store, now: time.Now}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Size <= 0 {
+		p.opts.Size = DefaultPoolSize
+	}
+	if p.opts.Label == "" {
+		p.opts.Label = "Address pool"
+	}
+	if p.opts.Timeout <= 0 {
+		p.opts.Timeout = DefaultPoolTimeout
+	}
+	if p.opts.Interval <= 0 {
+		p.opts.Interval = coinjar.DefaultWatchInterval
+	}
+	return p
+}
+
+// load reads the store on first use. p.mu must be held.
+func (p *AddressPool) load() error {
+	if p.loaded {
+		return nil
+	}
+	addresses, err := p.store.PoolAddresses()
+	if err != nil {
+		return err
+	}
+	p.addresses = make(map[string]PoolAddress, len(addresses))
+	for _, a := range addresses {
+		p.addresses[a.Address] = a
+		if a.State == AddressReady {
+			p.ready = append(p.ready, a.Address)
+		}
+	}
+	p.loaded = true
+	return nil
+}
+
+// put stores a and then records it. p.mu must be held.
+func (p *AddressPool) put(a PoolAddress) error {
+	if err := p.store.PutPoolAddress(a); err != nil {
+		return err
+	}
+	p.addresses[a.Address] = a
+	if a.State == AddressReady {
+		p.ready = append(p.ready, a.Address)
+	}
+	return nil
+}
+
+// Ready returns the number of addresses waiting to be handed out.
+func (p *AddressPool) Ready() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.load(); err != nil {
+		return 0, err
+	}
+	return len(p.ready), nil
+}
+
+// Acquire hands out the oldest ready address to owner. Each address goes to
+// one caller only. If none is ready it creates one.
+func (p *AddressPool) Acquire(ctx context.Context, owner string) (PoolAddress, error) {
+	p.mu.Lock()
+	if err := p.load(); err != nil {
+		p.mu.Unlock()
+		return PoolAddress{}, err
+	}
+	if len(p.ready) > 0 {
+		defer p.mu.Unlock()
+		a := p.addresses[p.ready[0]]
+		a.State = AddressAssigned
+		a.Owner = owner
+		a.AssignedAt = p.now()
+		if err := p.store.PutPoolAddress(a); err != nil {
+			return PoolAddress{}, err
+		}
+		p.ready = p.ready[1:]
+		p.addresses[a.Address] = a
+		return a, nil
+	}
+	p.mu.Unlock()
+
+	a, err := p.create(ctx)
+	if err != nil {
+		return PoolAddress{}, err
+	}
+	a.State = AddressAssigned
+	a.Owner = owner
+	a.AssignedAt = a.CreatedAt
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.put(a); err != nil {
+		return PoolAddress{}, err
+	}
+	return a, nil
+}
+
+func (p *AddressPool) create(ctx context.Context) (PoolAddress, error) {
+	created, err := p.client.CreateBitcoinAddressContext(ctx, p.opts.Label)
+	if err != nil {
+		return PoolAddress{}, err
+	}
+	return PoolAddress{
+		Address:   created.Address,
+		Label:     created.Label,
+		State:     AddressReady,
+		CreatedAt: p.now(),
+	}, nil
+}
+
+// Fill creates addresses until Options.Size are ready, and returns how many
+// it created.
+func (p *AddressPool) Fill(ctx context.Context) (int, error) {
+	p.fillMu.Lock()
+	defer p.fillMu.Unlock()
+
+	p.mu.Lock()
+	if err := p.load(); err != nil {
+		p.mu.Unlock()
+		return 0, err
+	}
+	need := p.opts.Size - len(p.ready)
+	p.mu.Unlock()
+
+	created := 0
+	for ; created < need; created++ {
+		a, err := p.create(ctx)
+		if err != nil {
+			return created, err
+		}
+		p.mu.Lock()
+		err = p.put(a)
+		p.mu.Unlock()
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// Recycle checks the addresses assigned for longer than Options.Timeout.
+// Those that have received nothing are made ready again, and the rest are
+// marked used. It returns the number made ready.
+func (p *AddressPool) Recycle(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	if err := p.load(); err != nil {
+		p.mu.Unlock()
+		return 0, err
+	}
+	now := p.now()
+	var due []PoolAddress
+	for _, a := range p.addresses {
+		if a.State == AddressAssigned && !now.Before(a.AssignedAt.Add(p.opts.Timeout)) {
+			due = append(due, a)
+		}
+	}
+	p.mu.Unlock()
+
+	recycled := 0
+	for _, a := range due {
+		info, err := p.client.BitcoinAddressContext(ctx, a.Address)
+		if err != nil {
+			return recycled, err
+		}
+		p.mu.Lock()
+		// Skip addresses another Recycle call got to first.
+		if current := p.addresses[a.Address]; current.State != AddressAssigned || !current.AssignedAt.Equal(a.AssignedAt) {
+			p.mu.Unlock()
+			continue
+		}
+		if info.TotalReceived.IsZero() {
+			a.State = AddressReady
+			a.Owner = ""
+			a.AssignedAt = time.Time{}
+		} else {
+			a.State = AddressUsed
+		}
+		err = p.put(a)
+		p.mu.Unlock()
+		if err != nil {
+			return recycled, err
+		}
+		if a.State == AddressReady {
+			recycled++
+		}
+	}
+	return recycled, nil
+}
+
+// Retire marks an assigned address used, so that Recycle never hands it out
+// again. Owners that may still be paid at the address after its timeout,
+// such as invoices, retire it to keep late payments from reaching the next
+// owner.
+func (p *AddressPool) Retire(address string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.load(); err != nil {
+		return err
+	}
+	a, ok := p.addresses[address]
+	if !ok {
+		return coinjar.ErrNotFound
+	}
+	if a.State != AddressAssigned {
+		return fmt.Errorf("invoice: pool address %s is %s, not assigned", address, a.State)
+	}
+	a.State = AddressUsed
+	return p.put(a)
+}
+
+// Adopt takes over addresses on the account that carry the pool's label,
+// have received nothing and are not yet in the pool, making them ready. It
+// recovers a pool whose store was lost. It returns the number adopted.
+func (p *AddressPool) Adopt(ctx context.Context) (int, error) {
+	all, err := p.client.AllBitcoinAddresses(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.load(); err != nil {
+		return 0, err
+	}
+	adopted := 0
+	for _, info := range all {
+		if info.Label != p.opts.Label || !info.TotalReceived.IsZero() {
+			continue
+		}
+		if _, ok := p.addresses[info.Address]; ok {
+			continue
+		}
+		a := PoolAddress{Address: info.Address, Label: info.Label, State: AddressReady, CreatedAt: p.now()}
+		if err := p.put(a); err != nil {
+			return adopted, err
+		}
+		adopted++
+	}
+	return adopted, nil
+}
+
+// Run recycles and refills the pool every Options.Interval until ctx is done.
+// It starts immediately and returns ctx.Err().
+func (p *AddressPool) Run(ctx context.Context) error {
+	for {
+		if _, err := p.Recycle(ctx); err != nil {
+			p.failed(ctx, err)
+		}
+		if _, err := p.Fill(ctx); err != nil {
+			p.failed(ctx, err)
+		}
+		timer := time.NewTimer(p.opts.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *AddressPool) failed(ctx context.Context, err error) {
+	if p.opts.OnError != nil && ctx.Err() == nil {
+		p.opts.OnError(err)
+	}
+}
@@ -0,0 +1,152 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+	"github.com/dteoh/coinjar-go/coinjar/coinjartest"
+)
+
+func TestPoolAcquire(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	ctx := context.Background()
+	pool := NewAddressPool(fake.Client(), NewMemoryStore(), &PoolOptions{Size: 5, Label: "Checkout"})
+
+	n, err := pool.Fill(ctx)
+	assertNil(t, err)
+	assertEqual(t, n, 5)
+	n, err = pool.Fill(ctx)
+	assertNil(t, err)
+	assertEqual(t, n, 0)
+	assertEqual(t, fake.BitcoinAddresses()[0].Label, "Checkout")
+
+	// Concurrent callers never share an address, and the pool creates more
+	// once it runs dry.
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			a, err := pool.Acquire(ctx, owner)
+			assertNil(t, err)
+			assertEqual(t, a.State, AddressAssigned)
+			assertEqual(t, a.Owner, owner)
+			mu.Lock()
+			seen[a.Address] = owner
+			mu.Unlock()
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+	assertEqual(t, len(seen), 8)
+	assertEqual(t, len(fake.BitcoinAddresses()), 8)
+	ready, err := pool.Ready()
+	assertNil(t, err)
+	assertEqual(t, ready, 0)
+}
+
+func TestPoolRecycle(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "invoice")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := OpenFileStore(filepath.Join(dir, "invoices.json"))
+	assertNil(t, err)
+	now := time.Date(2014, 4, 1, 12, 0, 0, 0, time.UTC)
+	pool := NewAddressPool(fake.Client(), store, &PoolOptions{Size: 2, Timeout: time.Hour})
+	pool.now = func() time.Time { return now }
+
+	_, err = pool.Fill(ctx)
+	assertNil(t, err)
+	unused, err := pool.Acquire(ctx, "order-1")
+	assertNil(t, err)
+	paid, err := pool.Acquire(ctx, "order-2")
+	assertNil(t, err)
+	fake.UpdateBitcoinAddress(coinjar.BitcoinAddress{Address: paid.Address, Label: paid.Label, TotalReceived: coinjar.MustParseAmount("0.1")})
+
+	// Nothing is due before the timeout.
+	n, err := pool.Recycle(ctx)
+	assertNil(t, err)
+	assertEqual(t, n, 0)
+
+	now = now.Add(time.Hour)
+	n, err = pool.Recycle(ctx)
+	assertNil(t, err)
+	assertEqual(t, n, 1)
+
+	// Assignments survive a restart: the recycled address is handed out
+	// again and the used one never is.
+	reopened, err := OpenFileStore(store.path)
+	assertNil(t, err)
+	restarted := NewAddressPool(fake.Client(), reopened, &PoolOptions{Size: 2, Timeout: time.Hour})
+	ready, err := restarted.Ready()
+	assertNil(t, err)
+	assertEqual(t, ready, 1)
+	a, err := restarted.Acquire(ctx, "order-3")
+	assertNil(t, err)
+	assertEqual(t, a.Address, unused.Address)
+
+	addresses, err := reopened.PoolAddresses()
+	assertNil(t, err)
+	states := make(map[string]PoolState)
+	for _, a := range addresses {
+		states[a.Address] = a.State
+	}
+	assertEqual(t, states[paid.Address], AddressUsed)
+	assertEqual(t, states[unused.Address], AddressAssigned)
+}
+
+func TestPoolAdopt(t *testing.T) {
+	fake := coinjartest.NewServer("someapikey")
+	defer fake.Close()
+	fake.AddBitcoinAddress(coinjar.BitcoinAddress{Label: "Address pool"})
+	fake.AddBitcoinAddress(coinjar.BitcoinAddress{Label: "Address pool", TotalReceived: coinjar.MustParseAmount("1")})
+	fake.AddBitcoinAddress(coinjar.BitcoinAddress{Label: "Donations"})
+	pool := NewAddressPool(fake.Client(), NewMemoryStore(), nil)
+
+	n, err := pool.Adopt(context.Background())
+	assertNil(t, err)
+	assertEqual(t, n, 1)
+	n, err = pool.Adopt(context.Background())
+	assertNil(t, err)
+	assertEqual(t, n, 0)
+}
+
+func TestManagerPool(t *testing.T) {
+	fake, m, _ := newManager(NewMemoryStore())
+	defer fake.Close()
+	ctx := context.Background()
+	m.opts.Pool = NewAddressPool(fake.Client(), NewMemoryStore(), &PoolOptions{Size: 1})
+	_, err := m.opts.Pool.Fill(ctx)
+	assertNil(t, err)
+	pooled := fake.BitcoinAddresses()[0]
+
+	inv, err := m.Create(ctx, Request{Reference: "Order 9", Currency: "BTC", Amount: coinjar.MustParseAmount("0.5")})
+	assertNil(t, err)
+	assertEqual(t, inv.Address, pooled.Address)
+	assertEqual(t, len(fake.BitcoinAddresses()), 1)
+
+	// The invoice's address is retired, so even unpaid and past the pool's
+	// timeout it is never handed to another invoice.
+	m.opts.Pool.now = func() time.Time { return time.Now().Add(DefaultPoolTimeout) }
+	n, err := m.opts.Pool.Recycle(ctx)
+	assertNil(t, err)
+	assertEqual(t, n, 0)
+	next, err := m.Create(ctx, Request{Reference: "Order 10", Currency: "BTC", Amount: coinjar.MustParseAmount("0.5")})
+	assertNil(t, err)
+	assertEqual(t, next.Address != inv.Address, true)
+
+	assertEqual(t, errors.Is(m.opts.Pool.Retire("missing"), coinjar.ErrNotFound), true)
+	assertEqual(t, m.opts.Pool.Retire(inv.Address) != nil, true)
+}
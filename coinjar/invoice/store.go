@@ -0,0 +1,234 @@
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+// Store keeps invoices. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get and ByAddress return coinjar.ErrNotFound when there is no such
+	// invoice.
+	Get(id string) (Invoice, error)
+	ByAddress(address string) (Invoice, error)
+	// Put adds inv or replaces the invoice with its ID.
+	Put(inv Invoice) error
+	// List returns every invoice, oldest first.
+	List() ([]Invoice, error)
+}
+
+// PoolStore keeps the addresses of an AddressPool. Implementations must be
+// safe for concurrent use.
+type PoolStore interface {
+	// PoolAddresses returns every address, oldest first.
+	PoolAddresses() ([]PoolAddress, error)
+	// PutPoolAddress adds a or replaces the entry for its address.
+	PutPoolAddress(a PoolAddress) error
+}
+
+// MemoryStore is a Store and PoolStore that lives only as long as the
+// process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	invoices  map[string]Invoice
+	addresses map[string]string
+	pool      map[string]PoolAddress
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		invoices:  make(map[string]Invoice),
+		addresses: make(map[string]string),
+		pool:      make(map[string]PoolAddress),
+	}
+}
+
+func (s *MemoryStore) Get(id string) (Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invoices[id]
+	if !ok {
+		return Invoice{}, coinjar.ErrNotFound
+	}
+	return inv.clone(), nil
+}
+
+func (s *MemoryStore) ByAddress(address string) (Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invoices[s.addresses[address]]
+	if !ok {
+		return Invoice{}, coinjar.ErrNotFound
+	}
+	return inv.clone(), nil
+}
+
+func (s *MemoryStore) Put(inv Invoice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(inv)
+	return nil
+}
+
+func (s *MemoryStore) put(inv Invoice) {
+	s.invoices[inv.ID] = inv.clone()
+	if inv.Address != "" {
+		s.addresses[inv.Address] = inv.ID
+	}
+}
+
+func (s *MemoryStore) List() ([]Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(), nil
+}
+
+func (s *MemoryStore) list() []Invoice {
+	out := make([]Invoice, 0, len(s.invoices))
+	for _, inv := range s.invoices {
+		out = append(out, inv.clone())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (s *MemoryStore) PoolAddresses() ([]PoolAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.poolAddresses(), nil
+}
+
+func (s *MemoryStore) poolAddresses() []PoolAddress {
+	out := make([]PoolAddress, 0, len(s.pool))
+	for _, a := range s.pool {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+func (s *MemoryStore) PutPoolAddress(a PoolAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pool[a.Address] = a
+	return nil
+}
+
+// clone copies the Transactions slice so callers cannot modify stored
+// invoices through it.
+func (inv Invoice) clone() Invoice {
+	inv.Transactions = append([]string(nil), inv.Transactions...)
+	return inv
+}
+
+// FileStore is a Store and PoolStore kept in a single JSON file. It holds
+// everything in memory and rewrites the file atomically on each change.
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+type storeFile struct {
+	Invoices []Invoice     `json:"invoices"`
+	Pool     []PoolAddress `json:"pool"`
+}
+
+// OpenFileStore loads the store in path, which need not exist yet.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	s.invoices = make(map[string]Invoice)
+	s.addresses = make(map[string]string)
+	s.pool = make(map[string]PoolAddress)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invoice: %s: %v", path, err)
+	}
+	for _, inv := range file.Invoices {
+		s.put(inv)
+	}
+	for _, a := range file.Pool {
+		s.pool[a.Address] = a
+	}
+	return s, nil
+}
+
+func (s *FileStore) Put(inv Invoice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.invoices[inv.ID]
+	s.put(inv)
+	if err := s.save(); err != nil {
+		// Leave memory matching the file.
+		if existed {
+			s.put(prev)
+		} else {
+			delete(s.invoices, inv.ID)
+		}
+		if inv.Address != prev.Address {
+			delete(s.addresses, inv.Address)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) PutPoolAddress(a PoolAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.pool[a.Address]
+	s.pool[a.Address] = a
+	if err := s.save(); err != nil {
+		if existed {
+			s.pool[a.Address] = prev
+		} else {
+			delete(s.pool, a.Address)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(storeFile{s.list(), s.poolAddresses()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
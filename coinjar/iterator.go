@@ -0,0 +1,215 @@
+package coinjar
+
+import "context"
+
+const DefaultPageSize = 100
+
+// MaxPageSize is the most records the API returns per call. Larger page
+// sizes are lowered to it, as a page cut short by the server would otherwise
+// look like the last one.
+const MaxPageSize = 100
+
+// PageOptions controls how an iterator walks a collection. The zero value
+// fetches every record, DefaultPageSize at a time.
+type PageOptions struct {
+	PageSize int // records requested per call, at most MaxPageSize
+	Max      int // stop after this many records, 0 for no cap
+}
+
+// pager walks limit/offset pages until the API returns a short page. fetch
+// loads a page into the typed iterator and reports how many records it holds.
+type pager struct {
+	ctx      context.Context
+	fetch    func(ctx context.Context, limit, offset int) (int, error)
+	pageSize int
+	max      int
+	offset   int
+	seen     int
+	i        int
+	n        int
+	last     bool
+	err      error
+}
+
+func newPager(ctx context.Context, opts *PageOptions, fetch func(context.Context, int, int) (int, error)) pager {
+	p := pager{ctx: ctx, fetch: fetch, pageSize: DefaultPageSize}
+	if opts != nil {
+		if opts.PageSize > 0 {
+			p.pageSize = opts.PageSize
+		}
+		if p.pageSize > MaxPageSize {
+			p.pageSize = MaxPageSize
+		}
+		p.max = opts.Max
+	}
+	return p
+}
+
+func (p *pager) next() bool {
+	if p.err != nil || (p.max > 0 && p.seen >= p.max) {
+		return false
+	}
+	p.i++
+	if p.i >= p.n {
+		if p.last {
+			return false
+		}
+		limit := p.pageSize
+		if p.max > 0 && p.max-p.seen < limit {
+			limit = p.max - p.seen
+		}
+		n, err := p.fetch(p.ctx, limit, p.offset)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.offset += n
+		p.i, p.n = 0, n
+		p.last = n < limit
+		if n == 0 {
+			return false
+		}
+	}
+	p.seen++
+	return true
+}
+
+// TransactionIterator walks every transaction on the account. Call Next
+// before each Value, and check Err once Next returns false.
+type TransactionIterator struct {
+	p    pager
+	page []Transaction
+}
+
+func (c *Client) IterTransactions(ctx context.Context, opts *PageOptions) *TransactionIterator {
+	it := new(TransactionIterator)
+	it.p = newPager(ctx, opts, func(ctx context.Context, limit, offset int) (n int, err error) {
+		it.page, err = c.ListTransactionsContext(ctx, limit, offset)
+		return len(it.page), err
+	})
+	return it
+}
+
+func (it *TransactionIterator) Next() bool         { return it.p.next() }
+func (it *TransactionIterator) Value() Transaction { return it.page[it.p.i] }
+func (it *TransactionIterator) Err() error         { return it.p.err }
+
+func (c *Client) AllTransactions(ctx context.Context, opts *PageOptions) (all []Transaction, err error) {
+	it := c.IterTransactions(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
+
+// PaymentIterator walks every payment on the account. Call Next before each
+// Value, and check Err once Next returns false.
+type PaymentIterator struct {
+	p    pager
+	page []Payment
+}
+
+func (c *Client) IterPayments(ctx context.Context, opts *PageOptions) *PaymentIterator {
+	it := new(PaymentIterator)
+	it.p = newPager(ctx, opts, func(ctx context.Context, limit, offset int) (n int, err error) {
+		it.page, err = c.ListPaymentsContext(ctx, limit, offset)
+		return len(it.page), err
+	})
+	return it
+}
+
+func (it *PaymentIterator) Next() bool     { return it.p.next() }
+func (it *PaymentIterator) Value() Payment { return it.page[it.p.i] }
+func (it *PaymentIterator) Err() error     { return it.p.err }
+
+func (c *Client) AllPayments(ctx context.Context, opts *PageOptions) (all []Payment, err error) {
+	it := c.IterPayments(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
+
+// ContactIterator walks every contact on the account. Call Next before each
+// Value, and check Err once Next returns false.
+type ContactIterator struct {
+	p    pager
+	page []Contact
+}
+
+func (c *Client) IterContacts(ctx context.Context, opts *PageOptions) *ContactIterator {
+	it := new(ContactIterator)
+	it.p = newPager(ctx, opts, func(ctx context.Context, limit, offset int) (n int, err error) {
+		it.page, err = c.ListContactsContext(ctx, limit, offset)
+		return len(it.page), err
+	})
+	return it
+}
+
+func (it *ContactIterator) Next() bool     { return it.p.next() }
+func (it *ContactIterator) Value() Contact { return it.page[it.p.i] }
+func (it *ContactIterator) Err() error     { return it.p.err }
+
+func (c *Client) AllContacts(ctx context.Context, opts *PageOptions) (all []Contact, err error) {
+	it := c.IterContacts(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
+
+// BitcoinAddressIterator walks every bitcoin address on the account. Call
+// Next before each Value, and check Err once Next returns false.
+type BitcoinAddressIterator struct {
+	p    pager
+	page []BitcoinAddress
+}
+
+func (c *Client) IterBitcoinAddresses(ctx context.Context, opts *PageOptions) *BitcoinAddressIterator {
+	it := new(BitcoinAddressIterator)
+	it.p = newPager(ctx, opts, func(ctx context.Context, limit, offset int) (n int, err error) {
+		it.page, err = c.ListBitcoinAddressesContext(ctx, limit, offset)
+		return len(it.page), err
+	})
+	return it
+}
+
+func (it *BitcoinAddressIterator) Next() bool            { return it.p.next() }
+func (it *BitcoinAddressIterator) Value() BitcoinAddress { return it.page[it.p.i] }
+func (it *BitcoinAddressIterator) Err() error            { return it.p.err }
+
+func (c *Client) AllBitcoinAddresses(ctx context.Context, opts *PageOptions) (all []BitcoinAddress, err error) {
+	it := c.IterBitcoinAddresses(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
+
+// OrderIterator walks every Checkout order. Call Next before each Value, and
+// check Err once Next returns false.
+type OrderIterator struct {
+	p    pager
+	page []Order
+}
+
+func (c *Client) IterOrders(ctx context.Context, opts *PageOptions) *OrderIterator {
+	it := new(OrderIterator)
+	it.p = newPager(ctx, opts, func(ctx context.Context, limit, offset int) (n int, err error) {
+		it.page, err = c.ListOrdersContext(ctx, limit, offset)
+		return len(it.page), err
+	})
+	return it
+}
+
+func (it *OrderIterator) Next() bool   { return it.p.next() }
+func (it *OrderIterator) Value() Order { return it.page[it.p.i] }
+func (it *OrderIterator) Err() error   { return it.p.err }
+
+func (c *Client) AllOrders(ctx context.Context, opts *PageOptions) (all []Order, err error) {
+	it := c.IterOrders(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
@@ -0,0 +1,143 @@
+package coinjar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pagedServer serves total records from path, honouring limit and offset,
+// and records the query of every request it receives.
+func pagedServer(t *testing.T, path, key string, total int, item func(i int) string) (*httptest.Server, *[]string) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		if r.URL.Path != path {
+			t.Errorf("Requested unexpected endpoint: %v", r.URL.Path)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var items []string
+		for i := offset; i < offset+limit && i < total; i++ {
+			items = append(items, item(i))
+		}
+		fmt.Fprintf(w, `{"%s": [%s]}`, key, strings.Join(items, ","))
+	}))
+	return ts, &queries
+}
+
+func TestIterTransactions(t *testing.T) {
+	ts, queries := pagedServer(t, "/transactions.json", "transactions", 5, func(i int) string {
+		return fmt.Sprintf(`{"id": %d}`, i)
+	})
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	it := client.IterTransactions(context.Background(), &PageOptions{PageSize: 2})
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Value().ID)
+	}
+	assertNil(t, it.Err())
+	assertEqual(t, fmt.Sprint(ids), "[0 1 2 3 4]")
+	assertEqual(t, strings.Join(*queries, " "), "limit=2&offset=0 limit=2&offset=2 limit=2&offset=4")
+}
+
+func TestIterStopsOnEmptyPage(t *testing.T) {
+	ts, queries := pagedServer(t, "/payments.json", "payments", 4, func(i int) string {
+		return fmt.Sprintf(`{"uuid": "%d"}`, i)
+	})
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	payments, err := client.AllPayments(context.Background(), &PageOptions{PageSize: 2})
+	assertNil(t, err)
+	assertEqual(t, len(payments), 4)
+	assertEqual(t, payments[3].UUID, "3")
+	assertEqual(t, strings.Join(*queries, " "), "limit=2&offset=0 limit=2&offset=2 limit=2&offset=4")
+}
+
+func TestIterMax(t *testing.T) {
+	ts, queries := pagedServer(t, "/contacts.json", "contacts", 10, func(i int) string {
+		return fmt.Sprintf(`{"name": "Contact %d"}`, i)
+	})
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	contacts, err := client.AllContacts(context.Background(), &PageOptions{PageSize: 3, Max: 4})
+	assertNil(t, err)
+	assertEqual(t, len(contacts), 4)
+	assertEqual(t, contacts[3].Name, "Contact 3")
+	assertEqual(t, strings.Join(*queries, " "), "limit=3&offset=0 limit=1&offset=3")
+}
+
+func TestIterDefaultPageSize(t *testing.T) {
+	ts, queries := pagedServer(t, "/bitcoin_addresses.json", "bitcoin_addresses", 150, func(i int) string {
+		return fmt.Sprintf(`{"address": "addr%d"}`, i)
+	})
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	addresses, err := client.AllBitcoinAddresses(context.Background(), nil)
+	assertNil(t, err)
+	assertEqual(t, len(addresses), 150)
+	assertEqual(t, addresses[149].Address, "addr149")
+	assertEqual(t, strings.Join(*queries, " "), "limit=100&offset=0 limit=100&offset=100")
+}
+
+func TestIterClampsPageSize(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		// The server caps pages at 100 records whatever the limit.
+		if limit > 100 {
+			limit = 100
+		}
+		var items []string
+		for i := offset; i < offset+limit && i < 250; i++ {
+			items = append(items, fmt.Sprintf(`{"id": %d}`, i))
+		}
+		fmt.Fprintf(w, `{"transactions": [%s]}`, strings.Join(items, ","))
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	transactions, err := client.AllTransactions(context.Background(), &PageOptions{PageSize: 500})
+	assertNil(t, err)
+	assertEqual(t, len(transactions), 250)
+	assertEqual(t, transactions[249].ID, 249)
+	assertEqual(t, strings.Join(queries, " "), "limit=100&offset=0 limit=100&offset=100 limit=100&offset=200")
+}
+
+func TestIterError(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits > 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"transactions": [{"id": 1}, {"id": 2}]}`)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	it := client.IterTransactions(context.Background(), &PageOptions{PageSize: 2})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assertEqual(t, count, 2)
+	assertEqual(t, errors.Is(it.Err(), ErrUnauthorized), true)
+	assertEqual(t, it.Next(), false)
+	assertEqual(t, hits, 2)
+}
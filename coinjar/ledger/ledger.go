@@ -0,0 +1,308 @@
+// Package ledger turns wallet activity into plain-text accounting journal
+// entries for Beancount and hledger.
+//
+// Every entry carries the UUID of the record it came from, so a journal can be
+// re-exported into without duplicating entries:
+//
+//	seen, err := ledger.ReadIDs(journal)
+//	w := ledger.NewWriter(journal, ledger.Beancount, ledger.Options{
+//		Accounts: accounts,
+//		Seen:     seen,
+//	})
+//	for it.Next() {
+//		if err := w.Transaction(it.Value()); err != nil {
+//			return err
+//		}
+//	}
+package ledger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+type Format int
+
+const (
+	Beancount Format = iota
+	HLedger
+)
+
+// Accounts maps wallet activity to journal accounts. The counter account of an
+// entry is the first of these to match: a reference rule, the contact the
+// counterparty belongs to, the counterparty itself, then Income or Expenses by
+// the direction of the money.
+type Accounts struct {
+	// Wallet is the asset account the CoinJar balance lives in. Defaults to
+	// "Assets:CoinJar".
+	Wallet string
+	// Income and Expenses are the fallbacks for money coming in and going
+	// out. They default to "Income:Uncategorized" and
+	// "Expenses:Uncategorized".
+	Income, Expenses string
+
+	// ByReference is tried in order against the entry's reference.
+	ByReference []ReferenceRule
+	// ByContact is keyed by contact UUID or name. Counterparties are matched
+	// to Contacts on their payee name, or on the contact name.
+	ByContact map[string]string
+	Contacts  []coinjar.Contact
+	// ByCounterparty is keyed by counterparty name, bitcoin address or, for
+	// payments, payee name.
+	ByCounterparty map[string]string
+}
+
+type ReferenceRule struct {
+	Pattern *regexp.Regexp
+	Account string
+}
+
+// Price is a FairRate snapshot used to annotate postings with their value.
+type Price struct {
+	Time     time.Time
+	Currency string
+	Rate     coinjar.FairRate
+}
+
+// Snapshot fetches the current fair rate for currency as a Price.
+func Snapshot(ctx context.Context, client *coinjar.Client, currency string) (Price, error) {
+	rate, err := client.FairRateContext(ctx, currency)
+	if err != nil {
+		return Price{}, err
+	}
+	return Price{Time: time.Now(), Currency: strings.ToUpper(currency), Rate: *rate}, nil
+}
+
+type Options struct {
+	Accounts Accounts
+	// Commodity is the symbol used for bitcoin amounts. Defaults to "BTC".
+	Commodity string
+	// Prices annotates each wallet posting with the spot price of the latest
+	// snapshot taken at or before the entry. Entries older than every
+	// snapshot are not annotated.
+	Prices []Price
+	// Seen holds the IDs of entries already in the journal, usually from
+	// ReadIDs. Entries with these IDs are skipped, and the IDs of written
+	// entries are added. It may be nil.
+	Seen map[string]bool
+}
+
+// Writer writes journal entries in one format.
+type Writer struct {
+	w         *bufio.Writer
+	format    Format
+	accounts  Accounts
+	commodity string
+	prices    []Price
+	seen      map[string]bool
+}
+
+func NewWriter(w io.Writer, format Format, opts Options) *Writer {
+	if opts.Commodity == "" {
+		opts.Commodity = "BTC"
+	}
+	if opts.Seen == nil {
+		opts.Seen = make(map[string]bool)
+	}
+	accounts := opts.Accounts
+	if accounts.Wallet == "" {
+		accounts.Wallet = "Assets:CoinJar"
+	}
+	if accounts.Income == "" {
+		accounts.Income = "Income:Uncategorized"
+	}
+	if accounts.Expenses == "" {
+		accounts.Expenses = "Expenses:Uncategorized"
+	}
+	prices := append([]Price(nil), opts.Prices...)
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].Time.Before(prices[j].Time) })
+	return &Writer{
+		w:         bufio.NewWriter(w),
+		format:    format,
+		accounts:  accounts,
+		commodity: opts.Commodity,
+		prices:    prices,
+		seen:      opts.Seen,
+	}
+}
+
+// entry is the format-independent form of a journal entry.
+type entry struct {
+	id        string
+	date      time.Time
+	pending   bool
+	payee     string
+	narration string
+	txid      string
+	amount    coinjar.Amount
+	account   string
+}
+
+// Transaction writes an entry for t. A transaction made by a payment has the
+// payment's ID, so exporting both the payment and its transaction yields one
+// entry. Cancelled and failed transactions are skipped.
+func (w *Writer) Transaction(t coinjar.Transaction) error {
+	if t.Status == coinjar.TransactionCancelled || t.Status == coinjar.TransactionFailed {
+		return nil
+	}
+	e := entry{
+		id:        t.UUID,
+		date:      t.CreatedAt.Time,
+		pending:   t.Status.IsPending(),
+		payee:     t.CounterpartyName,
+		narration: t.Reference,
+		txid:      t.BitcoinTxid,
+		amount:    t.Amount,
+	}
+	if t.RelatedPaymentUUID != "" {
+		e.id = t.RelatedPaymentUUID
+	}
+	if e.payee == "" {
+		e.payee = t.CounterpartyAddress
+	}
+	e.account = w.counterAccount(e.amount, t.Reference, t.CounterpartyName, t.CounterpartyAddress)
+	return w.write(e)
+}
+
+// Payment writes an entry for p, an outgoing payment. Payments that have not
+// been confirmed, or were cancelled or failed, are skipped.
+func (w *Writer) Payment(p coinjar.Payment) error {
+	if p.Status != coinjar.PaymentCompleted && p.Status != coinjar.PaymentProcessing {
+		return nil
+	}
+	e := entry{
+		id:        p.UUID,
+		date:      p.CreatedAt.Time,
+		pending:   p.Status.IsPending(),
+		payee:     p.PayeeName,
+		narration: p.Reference,
+		amount:    p.Amount.Abs().Neg(),
+	}
+	if p.RelatedTransaction != nil {
+		e.txid = p.RelatedTransaction.BitcoinTxid
+	}
+	e.account = w.counterAccount(e.amount, p.Reference, p.PayeeName)
+	return w.write(e)
+}
+
+// Flush writes any buffered entries to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) counterAccount(amount coinjar.Amount, reference string, counterparties ...string) string {
+	for _, rule := range w.accounts.ByReference {
+		if reference != "" && rule.Pattern.MatchString(reference) {
+			return rule.Account
+		}
+	}
+	for _, c := range counterparties {
+		if c == "" {
+			continue
+		}
+		for _, contact := range w.accounts.Contacts {
+			if contact.PayeeName != c && contact.Name != c {
+				continue
+			}
+			if account, ok := w.accounts.ByContact[contact.UUID]; ok {
+				return account
+			}
+			if account, ok := w.accounts.ByContact[contact.Name]; ok {
+				return account
+			}
+		}
+	}
+	for _, c := range counterparties {
+		if account, ok := w.accounts.ByCounterparty[c]; ok && c != "" {
+			return account
+		}
+	}
+	if amount.Sign() < 0 {
+		return w.accounts.Expenses
+	}
+	return w.accounts.Income
+}
+
+// price returns the latest snapshot at or before t.
+func (w *Writer) price(t time.Time) (Price, bool) {
+	i := sort.Search(len(w.prices), func(i int) bool { return w.prices[i].Time.After(t) })
+	if i == 0 {
+		return Price{}, false
+	}
+	return w.prices[i-1], true
+}
+
+func (w *Writer) write(e entry) error {
+	if e.id == "" {
+		return fmt.Errorf("ledger: entry on %s has no UUID", e.date.Format("2006-01-02"))
+	}
+	if w.seen[e.id] {
+		return nil
+	}
+	w.seen[e.id] = true
+
+	posting := e.amount.String() + " " + w.commodity
+	if p, ok := w.price(e.date); ok {
+		posting += " @ " + p.Rate.Spot.String() + " " + p.Currency
+	}
+	flag := "*"
+	if e.pending {
+		flag = "!"
+	}
+	date := e.date.Format("2006-01-02")
+
+	if w.format == HLedger {
+		description := oneLine(e.payee)
+		if e.narration != "" {
+			description += " | " + oneLine(e.narration)
+		}
+		tags := "uuid:" + e.id
+		if e.txid != "" {
+			tags += ", txid:" + e.txid
+		}
+		fmt.Fprintf(w.w, "%s %s %s  ; %s\n", date, flag, description, tags)
+		fmt.Fprintf(w.w, "    %s  %s\n", w.accounts.Wallet, posting)
+		fmt.Fprintf(w.w, "    %s\n\n", e.account)
+	} else {
+		fmt.Fprintf(w.w, "%s %s %s %s\n", date, flag, quote(e.payee), quote(e.narration))
+		fmt.Fprintf(w.w, "  uuid: %s\n", quote(e.id))
+		if e.txid != "" {
+			fmt.Fprintf(w.w, "  txid: %s\n", quote(e.txid))
+		}
+		fmt.Fprintf(w.w, "  %s  %s\n", w.accounts.Wallet, posting)
+		fmt.Fprintf(w.w, "  %s\n\n", e.account)
+	}
+	return nil
+}
+
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(oneLine(s))
+	return `"` + s + `"`
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var idPattern = regexp.MustCompile(`uuid:\s*"?([0-9A-Za-z-]+)"?`)
+
+// ReadIDs collects the entry IDs in a journal written by either format, for
+// Options.Seen.
+func ReadIDs(r io.Reader) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if m := idPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			ids[m[1]] = true
+		}
+	}
+	return ids, scanner.Err()
+}
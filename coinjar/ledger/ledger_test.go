@@ -0,0 +1,180 @@
+package ledger
+
+import (
+	"bytes"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dteoh/coinjar-go/coinjar"
+)
+
+func timestamp(s string) coinjar.Timestamp {
+	ts, err := coinjar.ParseTimestamp(s)
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+var (
+	received = coinjar.Transaction{
+		UUID:             "8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c",
+		Amount:           coinjar.MustParseAmount("0.5"),
+		Status:           coinjar.TransactionReceived,
+		CounterpartyName: "Customer \"Big\" Inc",
+		BitcoinTxid:      "d5f7a0c1",
+		Reference:        "Invoice 42",
+		CreatedAt:        timestamp("2014-04-01T12:00:00.000+10:00"),
+	}
+	payment = coinjar.Payment{
+		UUID:      "2a0e3bb4-ba5f-4f29-8c3f-1c4a9a1e5a11",
+		Amount:    coinjar.MustParseAmount("0.125"),
+		Status:    coinjar.PaymentCompleted,
+		PayeeName: "ryan@coinjar.io",
+		Reference: "Lunch",
+		CreatedAt: timestamp("2014-04-03T09:30:00.000+10:00"),
+		RelatedTransaction: &coinjar.Transaction{
+			UUID:        "1f3bd8e2-6c5e-4b5a-9a5d-7c0f3d6e9b21",
+			BitcoinTxid: "77aa01",
+		},
+	}
+	prices = []Price{
+		{Time: time.Date(2014, 4, 2, 0, 0, 0, 0, time.UTC), Currency: "USD", Rate: coinjar.FairRate{Spot: coinjar.MustParseAmount("450.5")}},
+		{Time: time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Rate: coinjar.FairRate{Spot: coinjar.MustParseAmount("560")}},
+	}
+)
+
+func TestBeancount(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Beancount, Options{Prices: prices})
+	assertNil(t, w.Transaction(received))
+	assertNil(t, w.Payment(payment))
+	assertNil(t, w.Flush())
+
+	assertEqual(t, buf.String(), `2014-04-01 * "Customer \"Big\" Inc" "Invoice 42"
+  uuid: "8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c"
+  txid: "d5f7a0c1"
+  Assets:CoinJar  0.5 BTC @ 560.0 USD
+  Income:Uncategorized
+
+2014-04-03 * "ryan@coinjar.io" "Lunch"
+  uuid: "2a0e3bb4-ba5f-4f29-8c3f-1c4a9a1e5a11"
+  txid: "77aa01"
+  Assets:CoinJar  -0.125 BTC @ 450.5 USD
+  Expenses:Uncategorized
+
+`)
+}
+
+func TestHLedger(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, HLedger, Options{Commodity: "XBT"})
+	pending := received
+	pending.Status = coinjar.TransactionUnconfirmed
+	assertNil(t, w.Transaction(pending))
+	assertNil(t, w.Flush())
+
+	assertEqual(t, buf.String(), `2014-04-01 ! Customer "Big" Inc | Invoice 42  ; uuid:8e9e9b8a-2f07-4cbc-92f2-ec3e1a8d1d7c, txid:d5f7a0c1
+    Assets:CoinJar  0.5 XBT
+    Income:Uncategorized
+
+`)
+}
+
+func TestAccountMappings(t *testing.T) {
+	accounts := Accounts{
+		Wallet:   "Assets:Crypto:CoinJar",
+		Expenses: "Expenses:Misc",
+		ByReference: []ReferenceRule{
+			{Pattern: regexp.MustCompile(`^Invoice \d+$`), Account: "Income:Sales"},
+		},
+		Contacts:       []coinjar.Contact{{UUID: "e359fd02", Name: "Ryan Zhou", PayeeName: "ryan@coinjar.io"}},
+		ByContact:      map[string]string{"e359fd02": "Expenses:Staff"},
+		ByCounterparty: map[string]string{"mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR": "Expenses:Hosting"},
+	}
+	hosting := coinjar.Transaction{
+		UUID:                "5c4b",
+		Amount:              coinjar.MustParseAmount("-0.01"),
+		Status:              coinjar.TransactionSent,
+		CounterpartyAddress: "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+	}
+	other := coinjar.Transaction{UUID: "9d1e", Amount: coinjar.MustParseAmount("-0.02"), Status: coinjar.TransactionSent}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, HLedger, Options{Accounts: accounts})
+	assertNil(t, w.Transaction(received))
+	assertNil(t, w.Payment(payment))
+	assertNil(t, w.Transaction(hosting))
+	assertNil(t, w.Transaction(other))
+	assertNil(t, w.Flush())
+
+	out := buf.String()
+	assertEqual(t, strings.Count(out, "    Assets:Crypto:CoinJar  "), 4)
+	assertEqual(t, strings.Contains(out, "    Income:Sales\n"), true)
+	assertEqual(t, strings.Contains(out, "    Expenses:Staff\n"), true)
+	assertEqual(t, strings.Contains(out, "    Expenses:Hosting\n"), true)
+	assertEqual(t, strings.Contains(out, "    Expenses:Misc\n"), true)
+}
+
+func TestReExportSkipsSeen(t *testing.T) {
+	for _, format := range []Format{Beancount, HLedger} {
+		var first bytes.Buffer
+		w := NewWriter(&first, format, Options{})
+		assertNil(t, w.Transaction(received))
+		assertNil(t, w.Payment(payment))
+		assertNil(t, w.Flush())
+
+		seen, err := ReadIDs(bytes.NewReader(first.Bytes()))
+		assertNil(t, err)
+		assertEqual(t, len(seen), 2)
+
+		// The payment's own transaction maps to the payment's entry.
+		related := coinjar.Transaction{
+			UUID:               payment.RelatedTransaction.UUID,
+			RelatedPaymentUUID: payment.UUID,
+			Amount:             coinjar.MustParseAmount("-0.125"),
+			Status:             coinjar.TransactionSent,
+		}
+		var second bytes.Buffer
+		w = NewWriter(&second, format, Options{Seen: seen})
+		assertNil(t, w.Transaction(received))
+		assertNil(t, w.Transaction(related))
+		assertNil(t, w.Payment(payment))
+		assertNil(t, w.Flush())
+		assertEqual(t, second.Len(), 0)
+	}
+}
+
+func TestSkippedStatuses(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Beancount, Options{})
+	cancelled := received
+	cancelled.Status = coinjar.TransactionCancelled
+	assertNil(t, w.Transaction(cancelled))
+	pending := payment
+	pending.Status = coinjar.PaymentPending
+	assertNil(t, w.Payment(pending))
+	assertNil(t, w.Flush())
+	assertEqual(t, buf.Len(), 0)
+
+	assertEqual(t, w.Transaction(coinjar.Transaction{Status: coinjar.TransactionReceived}) != nil, true)
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'nil' at %v:%v failed\n\tActual: %v", file, line, actual)
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	if actual == expected {
+		return
+	}
+	_, file, line, _ := runtime.Caller(1)
+	t.Errorf("Assertion 'equal' at %v:%v failed\n\tActual: %v\n\tExpected: %v", file, line, actual, expected)
+}
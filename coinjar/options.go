@@ -0,0 +1,129 @@
+package coinjar
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultEndpoint  = "https://api.coinjar.io/v1"
+	CheckoutEndpoint = "https://checkout.coinjar.io/api/v1"
+	DefaultUserAgent = "coinjar-go"
+	DefaultTimeout   = 30 * time.Second
+)
+
+// Logger receives a line for every request made by the client. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// RateLimiter is consulted before every request is sent. Wait should block
+// until the request may proceed or ctx is done. Limiters that also implement
+// GroupRateLimiter, such as *Limiter, are told which endpoint group each
+// request is for and when the server throttles it.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+type Option func(*options)
+
+type options struct {
+	endpoint    string
+	userAgent   string
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	timeout     time.Duration
+	hasTimeout  bool
+	retryPolicy RetryPolicy
+	logger      Logger
+	limiter     RateLimiter
+}
+
+// New returns a client for the API key, configured by opts. Without options
+// it talks to the production API with a 30 second timeout and no retries.
+func New(apiKey string, opts ...Option) *Client {
+	o := options{
+		endpoint:  DefaultEndpoint,
+		userAgent: DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var httpClient http.Client
+	if o.httpClient != nil {
+		httpClient = *o.httpClient
+	} else {
+		httpClient.Timeout = DefaultTimeout
+	}
+	if o.transport != nil {
+		httpClient.Transport = o.transport
+	}
+	if o.hasTimeout {
+		httpClient.Timeout = o.timeout
+	}
+
+	return &Client{
+		apiKey:      apiKey,
+		endpoint:    o.endpoint,
+		userAgent:   o.userAgent,
+		httpClient:  &httpClient,
+		retryPolicy: o.retryPolicy,
+		logger:      o.logger,
+		limiter:     o.limiter,
+	}
+}
+
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithHTTPClient makes the client send requests through a copy of hc, so
+// WithTransport and WithTimeout never modify the caller's client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = hc
+	}
+}
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithTimeout limits the total time of a single attempt. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+		o.hasTimeout = true
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.userAgent = ua
+	}
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = p
+	}
+}
+
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func WithRateLimiter(l RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
@@ -0,0 +1,131 @@
+package coinjar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type countingLimiter struct {
+	calls int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.calls++
+	return l.err
+}
+
+func TestNewDefaults(t *testing.T) {
+	client := New("someapikey")
+	assertEqual(t, client.endpoint, DefaultEndpoint)
+	assertEqual(t, client.userAgent, DefaultUserAgent)
+	assertEqual(t, client.httpClient.Timeout, DefaultTimeout)
+	assertNil(t, client.retryPolicy)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	var buf bytes.Buffer
+	limiter := new(countingLimiter)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		assertEqual(t, r.Header.Get("User-Agent"), "payouts/1.0")
+		assertEqual(t, r.URL.Path, "/fair_rate/AUD.json")
+		fmt.Fprint(w, `{"bid": "1", "ask": "2", "spot": "1.5"}`)
+	}))
+	defer ts.Close()
+
+	client := New("someapikey",
+		WithEndpoint(ts.URL),
+		WithUserAgent("payouts/1.0"),
+		WithLogger(log.New(&buf, "", 0)),
+		WithRateLimiter(limiter))
+	rate, err := client.FairRate("AUD")
+	assertNil(t, err)
+	assertEqual(t, rate.Spot.String(), "1.5")
+	assertEqual(t, limiter.calls, 1)
+	assertEqual(t, strings.HasPrefix(buf.String(), "coinjar: GET fair_rate/AUD.json 200 in "), true)
+}
+
+func TestWithTransport(t *testing.T) {
+	hc := &http.Client{Timeout: time.Minute}
+	client := New("someapikey",
+		WithEndpoint("http://coinjar.invalid"),
+		WithHTTPClient(hc),
+		WithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			assertEqual(t, r.URL.String(), "http://coinjar.invalid/account.json")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader(`{"user": {"email": "test@example.com"}}`)),
+			}, nil
+		})),
+		WithTimeout(5*time.Second))
+
+	user, err := client.Account()
+	assertNil(t, err)
+	assertEqual(t, user.Email, "test@example.com")
+	assertEqual(t, client.httpClient.Timeout, 5*time.Second)
+	assertEqual(t, hc.Timeout, time.Minute)
+	assertNil(t, hc.Transport)
+}
+
+func TestWithTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	client := New("someapikey", WithEndpoint(ts.URL), WithTimeout(50*time.Millisecond))
+	_, err := client.Account()
+	assertNotNil(t, err)
+}
+
+func TestWithRateLimiterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request should not have been sent: %v", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	limiter := &countingLimiter{err: context.Canceled}
+	client := New("someapikey", WithEndpoint(ts.URL), WithRateLimiter(limiter))
+	_, err := client.Account()
+	assertEqual(t, errors.Is(err, context.Canceled), true)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"user": {"email": "test@example.com"}}`)
+	}))
+	defer ts.Close()
+
+	client := New("someapikey", WithEndpoint(ts.URL), WithRetryPolicy(fastBackoff()))
+	_, err := client.Account()
+	assertNil(t, err)
+	assertEqual(t, hits, 2)
+}
@@ -0,0 +1,154 @@
+package coinjar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// OrderItem is a line item on a Checkout order. Amount is the unit price in
+// the order's currency.
+type OrderItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Amount   Amount `json:"amount"`
+}
+
+// Order is a CoinJar Checkout order. The buyer pays BitcoinAmount to
+// BitcoinAddress before ExpiresAt.
+type Order struct {
+	UUID              string      `json:"uuid"`
+	Status            OrderStatus `json:"status"`
+	Currency          string      `json:"currency"`
+	Amount            Amount      `json:"amount"`
+	BitcoinAmount     Amount      `json:"bitcoin_amount"`
+	BitcoinAddress    string      `json:"bitcoin_address"`
+	MerchantInvoice   string      `json:"merchant_invoice"`
+	MerchantReference string      `json:"merchant_reference"`
+	Items             []OrderItem `json:"order_items"`
+	ReturnURL         string      `json:"return_url"`
+	CancelURL         string      `json:"cancel_url"`
+	NotifyURL         string      `json:"notify_url"`
+	CreatedAt         Timestamp   `json:"created_at"`
+	UpdatedAt         Timestamp   `json:"updated_at"`
+	ExpiresAt         Timestamp   `json:"expires_at"`
+}
+
+// OrderRequest describes a Checkout order. Currency and at least one item are
+// required. The buyer is sent to ReturnURL after paying or CancelURL after
+// giving up, and NotifyURL receives status notifications.
+type OrderRequest struct {
+	Currency          string
+	Items             []OrderItem
+	MerchantInvoice   string
+	MerchantReference string
+	ReturnURL         string
+	CancelURL         string
+	NotifyURL         string
+}
+
+func (r *OrderRequest) params() ([]string, error) {
+	if r.Currency == "" {
+		return nil, errors.New("coinjar: order needs a currency")
+	}
+	if len(r.Items) == 0 {
+		return nil, errors.New("coinjar: order needs at least one item")
+	}
+	params := []string{"order[currency]", r.Currency}
+	for i, item := range r.Items {
+		if item.Name == "" || item.Quantity <= 0 || item.Amount.Sign() <= 0 {
+			return nil, errors.New("coinjar: order item " + strconv.Itoa(i) + " needs a name, positive quantity and positive amount")
+		}
+		prefix := "order[order_items_attributes][" + strconv.Itoa(i) + "]"
+		params = append(params,
+			prefix+"[name]", item.Name,
+			prefix+"[quantity]", strconv.Itoa(item.Quantity),
+			prefix+"[amount]", item.Amount.String())
+	}
+	for _, field := range []struct{ key, value string }{
+		{"order[merchant_invoice]", r.MerchantInvoice},
+		{"order[merchant_reference]", r.MerchantReference},
+		{"order[return_url]", r.ReturnURL},
+		{"order[cancel_url]", r.CancelURL},
+		{"order[notify_url]", r.NotifyURL},
+	} {
+		if field.value != "" {
+			params = append(params, field.key, field.value)
+		}
+	}
+	return params, nil
+}
+
+// CreateOrder creates a Checkout order awaiting payment. Checkout is served
+// from CheckoutEndpoint and authenticated with the merchant's API key, so
+// orders need a client of their own:
+//
+//	checkout := coinjar.New(merchantKey, coinjar.WithEndpoint(coinjar.CheckoutEndpoint))
+func (c *Client) CreateOrder(req OrderRequest) (*Order, error) {
+	return c.CreateOrderContext(context.Background(), req)
+}
+
+func (c *Client) CreateOrderContext(ctx context.Context, req OrderRequest) (obj *Order, err error) {
+	params, err := req.params()
+	if err != nil {
+		return
+	}
+	body, err := c.send(ctx, "POST", "orders.json", params...)
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Order *Order }
+	err = json.Unmarshal(body, &wrapper)
+	if err != nil {
+		return
+	}
+	return wrapper.Order, nil
+}
+
+func (c *Client) Order(uuid string) (*Order, error) {
+	return c.OrderContext(context.Background(), uuid)
+}
+
+func (c *Client) OrderContext(ctx context.Context, uuid string) (obj *Order, err error) {
+	body, err := c.read(ctx, "orders/"+uuid+".json")
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Order *Order }
+	err = json.Unmarshal(body, &wrapper)
+	if err != nil {
+		return
+	}
+	return wrapper.Order, nil
+}
+
+func (c *Client) Orders() ([]Order, error) {
+	return c.ListOrders(100, 0)
+}
+
+func (c *Client) OrdersContext(ctx context.Context) ([]Order, error) {
+	return c.ListOrdersContext(ctx, 100, 0)
+}
+
+func (c *Client) ListOrders(limit, offset int) ([]Order, error) {
+	return c.ListOrdersContext(context.Background(), limit, offset)
+}
+
+func (c *Client) ListOrdersContext(ctx context.Context, limit, offset int) (obj []Order, err error) {
+	body, err := c.read(ctx, "orders.json",
+		"limit", strconv.Itoa(limit),
+		"offset", strconv.Itoa(offset))
+	if err != nil {
+		return
+	}
+
+	var wrapper struct{ Orders []Order }
+	err = json.Unmarshal(body, &wrapper)
+	if err != nil {
+		return
+	}
+	return wrapper.Orders, nil
+}
@@ -0,0 +1,121 @@
+package coinjar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const orderJSON = `
+	{
+		"uuid": "c2d5b4a0-7a4e-4b6f-9d7a-2f1b3c4d5e6f",
+		"status": "UNPAID",
+		"currency": "AUD",
+		"amount": "25.5",
+		"bitcoin_amount": "0.04213",
+		"bitcoin_address": "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR",
+		"merchant_invoice": "INV-42",
+		"merchant_reference": "cart-9",
+		"order_items": [
+			{"name": "T-shirt", "quantity": 2, "amount": "10.0"},
+			{"name": "Sticker", "quantity": 1, "amount": "5.5"}
+		],
+		"return_url": "https://shop.example.com/thanks",
+		"cancel_url": "https://shop.example.com/cart",
+		"notify_url": "https://shop.example.com/coinjar/notify",
+		"created_at": "2014-04-01T10:00:00.000+10:00",
+		"updated_at": "2014-04-01T10:00:00.000+10:00",
+		"expires_at": "2014-04-01T10:15:00.000+10:00"
+	}
+`
+
+func TestCreateOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "merchantkey")
+		if url := r.URL.Path; url == "/orders.json" {
+			assertEqual(t, r.Method, "POST")
+			assertEqual(t, r.FormValue("order[currency]"), "AUD")
+			assertEqual(t, r.FormValue("order[order_items_attributes][0][name]"), "T-shirt")
+			assertEqual(t, r.FormValue("order[order_items_attributes][0][quantity]"), "2")
+			assertEqual(t, r.FormValue("order[order_items_attributes][0][amount]"), "10.0")
+			assertEqual(t, r.FormValue("order[order_items_attributes][1][name]"), "Sticker")
+			assertEqual(t, r.FormValue("order[merchant_invoice]"), "INV-42")
+			assertEqual(t, r.FormValue("order[return_url]"), "https://shop.example.com/thanks")
+			assertEqual(t, r.FormValue("order[notify_url]"), "https://shop.example.com/coinjar/notify")
+			_, ok := r.Form["order[merchant_reference]"]
+			assertEqual(t, ok, false)
+			fmt.Fprintf(w, `{"order": %s}`, orderJSON)
+		} else {
+			t.Errorf("Requested unexpected endpoint: %v", url)
+		}
+	}))
+	defer ts.Close()
+
+	client := New("merchantkey", WithEndpoint(ts.URL))
+	order, err := client.CreateOrder(OrderRequest{
+		Currency: "AUD",
+		Items: []OrderItem{
+			{Name: "T-shirt", Quantity: 2, Amount: MustParseAmount("10")},
+			{Name: "Sticker", Quantity: 1, Amount: MustParseAmount("5.5")},
+		},
+		MerchantInvoice: "INV-42",
+		ReturnURL:       "https://shop.example.com/thanks",
+		CancelURL:       "https://shop.example.com/cart",
+		NotifyURL:       "https://shop.example.com/coinjar/notify",
+	})
+	assertNil(t, err)
+
+	assertEqual(t, order.Status, OrderUnpaid)
+	assertEqual(t, order.Amount.String(), "25.5")
+	assertEqual(t, order.BitcoinAmount.String(), "0.04213")
+	assertEqual(t, order.BitcoinAddress, "mgk4K3gdBKRDUJ27jB1VzAATH4upGquYDR")
+	assertEqual(t, len(order.Items), 2)
+	assertEqual(t, order.Items[0].Quantity, 2)
+	assertEqual(t, order.ExpiresAt.Sub(order.CreatedAt.Time).Minutes(), 15.0)
+}
+
+func TestCreateOrderValidation(t *testing.T) {
+	client := NewCustomClient("merchantkey", "http://unused")
+	for _, req := range []OrderRequest{
+		{Items: []OrderItem{{Name: "T-shirt", Quantity: 1, Amount: MustParseAmount("10")}}},
+		{Currency: "AUD"},
+		{Currency: "AUD", Items: []OrderItem{{Name: "T-shirt", Amount: MustParseAmount("10")}}},
+	} {
+		_, err := client.CreateOrder(req)
+		assertNotNil(t, err)
+	}
+}
+
+func TestOrderAndListOrders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "merchantkey")
+		switch r.URL.Path {
+		case "/orders.json":
+			assertEqual(t, r.URL.Query().Get("limit"), "10")
+			assertEqual(t, r.URL.Query().Get("offset"), "5")
+			fmt.Fprintf(w, `{"orders": [%s]}`, orderJSON)
+		case "/orders/c2d5b4a0-7a4e-4b6f-9d7a-2f1b3c4d5e6f.json":
+			fmt.Fprintf(w, `{"order": %s}`, orderJSON)
+		case "/orders/missing.json":
+			fmt.Fprint(w, "null")
+		default:
+			t.Errorf("Requested unexpected endpoint: %v", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("merchantkey", ts.URL)
+	orders, err := client.ListOrders(10, 5)
+	assertNil(t, err)
+	assertEqual(t, len(orders), 1)
+	assertEqual(t, orders[0].MerchantReference, "cart-9")
+
+	order, err := client.Order("c2d5b4a0-7a4e-4b6f-9d7a-2f1b3c4d5e6f")
+	assertNil(t, err)
+	assertEqual(t, order.Currency, "AUD")
+
+	_, err = client.Order("missing")
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+}
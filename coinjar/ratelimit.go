@@ -0,0 +1,213 @@
+package coinjar
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointGroup names the family of API endpoints a request belongs to, the
+// first segment of its path.
+type EndpointGroup string
+
+const (
+	GroupAccount          EndpointGroup = "account"
+	GroupBitcoinAddresses EndpointGroup = "bitcoin_addresses"
+	GroupContacts         EndpointGroup = "contacts"
+	GroupPayments         EndpointGroup = "payments"
+	GroupTransactions     EndpointGroup = "transactions"
+	GroupFairRate         EndpointGroup = "fair_rate"
+)
+
+func endpointGroup(api string) EndpointGroup {
+	if i := strings.IndexByte(api, '/'); i >= 0 {
+		api = api[:i]
+	}
+	return EndpointGroup(strings.TrimSuffix(api, ".json"))
+}
+
+// GroupRateLimiter is a RateLimiter that limits endpoint groups separately
+// and hears about throttling. The client calls WaitGroup instead of Wait when
+// its limiter implements it, and Throttled after every 429 response, with the
+// server's Retry-After delay or zero.
+type GroupRateLimiter interface {
+	RateLimiter
+	WaitGroup(ctx context.Context, group EndpointGroup) error
+	Throttled(group EndpointGroup, retryAfter time.Duration)
+}
+
+// Limit is a token bucket: Rate tokens are added per second, up to Burst. A
+// Rate of zero or less means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRecoveryInterval is how long a throttled group must go without
+// another 429 before its rate is doubled back towards its limit.
+const DefaultRecoveryInterval = 30 * time.Second
+
+// Limiter is a GroupRateLimiter built from token buckets, one per endpoint
+// group. When the server throttles a group, the limiter pauses it for the
+// Retry-After delay and halves its rate. It restores the rate step by step
+// once the throttling stops. Limiter is safe for concurrent use.
+//
+//	limiter := coinjar.NewLimiter(coinjar.Limit{Rate: 5, Burst: 10})
+//	limiter.SetGroupLimit(coinjar.GroupPayments, coinjar.Limit{Rate: 1, Burst: 1})
+//	client := coinjar.New(key, coinjar.WithRateLimiter(limiter))
+type Limiter struct {
+	RecoveryInterval time.Duration
+
+	mu      sync.Mutex
+	limit   Limit
+	limits  map[EndpointGroup]Limit
+	buckets map[EndpointGroup]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	// last is when tokens were last added. While the group is paused it is
+	// pausedUntil, so that nothing accrues during the pause.
+	last time.Time
+	// factor scales limit.Rate while the group is being throttled.
+	factor      float64
+	adjusted    time.Time
+	pausedUntil time.Time
+}
+
+// NewLimiter returns a Limiter applying limit to every endpoint group that
+// has no limit of its own.
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		RecoveryInterval: DefaultRecoveryInterval,
+		limit:            limit,
+		limits:           make(map[EndpointGroup]Limit),
+		buckets:          make(map[EndpointGroup]*bucket),
+		now:              time.Now,
+	}
+}
+
+// SetGroupLimit gives group its own bucket, replacing any earlier one.
+func (l *Limiter) SetGroupLimit(group EndpointGroup, limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[group] = limit
+	delete(l.buckets, group)
+}
+
+// Wait takes a token from the shared bucket of groups without their own
+// limit.
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitGroup(ctx, "")
+}
+
+// WaitGroup blocks until group has a token or ctx is done. Callers are served
+// in the order they arrive.
+func (l *Limiter) WaitGroup(ctx context.Context, group EndpointGroup) error {
+	l.mu.Lock()
+	b := l.bucket(group)
+	if b == nil {
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+	now := l.now()
+	b.refill(now, l.RecoveryInterval)
+	// Reserve a token now, going into debt if need be, and wait for the
+	// debt to be repaid. Repayment starts once any pause is over.
+	b.tokens--
+	var delay time.Duration
+	if pause := b.last.Sub(now); pause > 0 {
+		delay = pause
+	}
+	if b.tokens < 0 {
+		delay += time.Duration(-b.tokens / b.rate() * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	if err := sleep(ctx, delay); err != nil {
+		l.mu.Lock()
+		b.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Throttled pauses group for retryAfter, or until one token would have been
+// added if it is zero, and halves the group's rate. No tokens are added
+// during the pause, and callers waiting on the group are then released one by
+// one at the reduced rate.
+func (l *Limiter) Throttled(group EndpointGroup, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(group)
+	if b == nil {
+		return
+	}
+	now := l.now()
+	b.refill(now, l.RecoveryInterval)
+	if b.factor > 1.0/32 {
+		b.factor /= 2
+	}
+	b.adjusted = now
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+	if retryAfter <= 0 {
+		retryAfter = time.Duration(float64(time.Second) / b.rate())
+	}
+	if until := now.Add(retryAfter); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	if b.pausedUntil.After(b.last) {
+		b.last = b.pausedUntil
+	}
+}
+
+// bucket returns the bucket for group, or nil if it is unlimited. Groups
+// without their own limit share the "" bucket.
+func (l *Limiter) bucket(group EndpointGroup) *bucket {
+	limit, ok := l.limits[group]
+	if !ok {
+		group, limit = "", l.limit
+	}
+	if limit.Rate <= 0 {
+		return nil
+	}
+	b, ok := l.buckets[group]
+	if !ok {
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: l.now(), factor: 1}
+		l.buckets[group] = b
+	}
+	return b
+}
+
+func (b *bucket) rate() float64 {
+	return b.limit.Rate * b.factor
+}
+
+func (b *bucket) refill(now time.Time, recovery time.Duration) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate()
+		if burst := float64(b.limit.Burst); b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+	for b.factor < 1 && recovery > 0 && now.Sub(b.adjusted) >= recovery {
+		b.factor *= 2
+		if b.factor > 1 {
+			b.factor = 1
+		}
+		b.adjusted = b.adjusted.Add(recovery)
+	}
+}
@@ -0,0 +1,169 @@
+package coinjar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEndpointGroup(t *testing.T) {
+	for api, group := range map[string]EndpointGroup{
+		"account.json":              GroupAccount,
+		"transactions.json":         GroupTransactions,
+		"payments/abc/confirm.json": GroupPayments,
+		"fair_rate/USD.json":        GroupFairRate,
+		"bitcoin_addresses.json":    GroupBitcoinAddresses,
+	} {
+		assertEqual(t, endpointGroup(api), group)
+	}
+}
+
+func TestLimiterBurstThenRate(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 100, Burst: 2})
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assertNil(t, limiter.WaitGroup(ctx, GroupTransactions))
+	}
+	// Two tokens up front, then one every 10ms.
+	assertEqual(t, time.Since(start) >= 25*time.Millisecond, true)
+}
+
+func TestLimiterGroups(t *testing.T) {
+	limiter := NewLimiter(Limit{})
+	limiter.SetGroupLimit(GroupPayments, Limit{Rate: 0.01, Burst: 1})
+	ctx := context.Background()
+
+	assertNil(t, limiter.WaitGroup(ctx, GroupPayments))
+	for i := 0; i < 100; i++ {
+		assertNil(t, limiter.WaitGroup(ctx, GroupTransactions))
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assertEqual(t, limiter.WaitGroup(short, GroupPayments), context.DeadlineExceeded)
+	// The cancelled caller gave its reservation back.
+	assertEqual(t, limiter.buckets[GroupPayments].tokens > -1, true)
+}
+
+func TestLimiterThrottled(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 1000, Burst: 10})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assertNil(t, limiter.WaitGroup(ctx, GroupContacts))
+	limiter.Throttled(GroupContacts, 20*time.Millisecond)
+	b := limiter.buckets[""]
+	assertEqual(t, b.rate(), 500.0)
+	assertEqual(t, b.pausedUntil, now.Add(20*time.Millisecond))
+
+	limiter.Throttled(GroupContacts, 0)
+	assertEqual(t, b.rate(), 250.0)
+
+	now = now.Add(DefaultRecoveryInterval)
+	b.refill(now, limiter.RecoveryInterval)
+	assertEqual(t, b.rate(), 500.0)
+	now = now.Add(2 * DefaultRecoveryInterval)
+	b.refill(now, limiter.RecoveryInterval)
+	assertEqual(t, b.rate(), 1000.0)
+}
+
+func TestLimiterPausesAfterThrottle(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 1000, Burst: 10})
+	limiter.Throttled(GroupAccount, 30*time.Millisecond)
+	start := time.Now()
+	assertNil(t, limiter.WaitGroup(context.Background(), GroupAccount))
+	assertEqual(t, time.Since(start) >= 30*time.Millisecond, true)
+}
+
+func TestLimiterSpreadsWaitersAfterThrottle(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 100, Burst: 1})
+	start := time.Now()
+	limiter.Throttled(GroupPayments, 50*time.Millisecond)
+
+	var mu sync.Mutex
+	var released []time.Duration
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assertNil(t, limiter.WaitGroup(context.Background(), GroupPayments))
+			mu.Lock()
+			released = append(released, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// The pause, then one token every 20ms at the halved rate.
+	sort.Slice(released, func(i, j int) bool { return released[i] < released[j] })
+	for i, d := range released {
+		assertEqual(t, d >= 50*time.Millisecond+time.Duration(i+1)*20*time.Millisecond, true)
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 1000, Burst: 5})
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assertNil(t, limiter.WaitGroup(context.Background(), GroupTransactions))
+		}()
+	}
+	wg.Wait()
+	assertEqual(t, time.Since(start) >= 20*time.Millisecond, true)
+}
+
+// recordingLimiter records the GroupRateLimiter calls made by a client.
+type recordingLimiter struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context) error {
+	panic("Wait called on a GroupRateLimiter")
+}
+
+func (l *recordingLimiter) WaitGroup(ctx context.Context, group EndpointGroup) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, "wait "+string(group))
+	return nil
+}
+
+func (l *recordingLimiter) Throttled(group EndpointGroup, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, fmt.Sprintf("throttled %s %v", group, retryAfter))
+}
+
+func TestClientReportsThrottling(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/payments.json" {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(429)
+			fmt.Fprint(w, `{"error": "Too Many Requests"}`)
+			return
+		}
+		fmt.Fprint(w, `{"transactions": []}`)
+	}))
+	defer ts.Close()
+
+	limiter := new(recordingLimiter)
+	client := New("someapikey", WithEndpoint(ts.URL), WithRateLimiter(limiter))
+	_, err := client.Transactions()
+	assertNil(t, err)
+	_, err = client.Payments()
+	assertNotNil(t, err)
+	assertEqual(t, fmt.Sprint(limiter.calls), "[wait transactions wait payments throttled payments 2s]")
+}
@@ -0,0 +1,127 @@
+package coinjar
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides whether a failed request should be attempted again.
+// attempt is the number of attempts made so far, starting at 1, and err is
+// the error the last attempt failed with. Only idempotent requests are ever
+// passed to a policy.
+type RetryPolicy interface {
+	Retry(attempt int, err error) (wait time.Duration, retry bool)
+}
+
+// ExponentialBackoff retries transport errors and the configured status codes,
+// doubling the delay after every attempt. A Retry-After header sent with the
+// response takes precedence over the computed delay.
+type ExponentialBackoff struct {
+	MaxAttempts     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	Jitter          float64 // fraction of the delay to randomise, 0 to 1
+	RetryableStatus []int
+}
+
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+func (p *ExponentialBackoff) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if !p.retryableStatus(apiErr.StatusCode) {
+			return 0, false
+		}
+		if wait, ok := parseRetryAfter(apiErr.Header.Get("Retry-After"), time.Now()); ok {
+			return wait, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+func (p *ExponentialBackoff) retryableStatus(code int) bool {
+	for _, s := range p.RetryableStatus {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ExponentialBackoff) backoff(attempt int) time.Duration {
+	wait := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || wait < p.MaxDelay); i++ {
+		wait *= 2
+	}
+	if p.MaxDelay > 0 && wait > p.MaxDelay {
+		wait = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		wait -= time.Duration(rand.Float64() * p.Jitter * float64(wait))
+	}
+	return wait
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a number
+// of seconds and an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := at.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
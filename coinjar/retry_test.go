@@ -0,0 +1,194 @@
+package coinjar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastBackoff() *ExponentialBackoff {
+	p := NewExponentialBackoff()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 5 * time.Millisecond
+	return p
+}
+
+func TestRetryOnBadGateway(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		assertEqual(t, r.URL.RawQuery, "limit=100&offset=0")
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"contacts": [{"uuid": "e359fd02-0079-4ef0-9f1f-706a84f39cca"}]}`)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	contacts, err := client.Contacts()
+	assertNil(t, err)
+	assertEqual(t, len(contacts), 1)
+	assertEqual(t, atomic.LoadInt32(&hits), int32(3))
+}
+
+func TestRetryOnConnectionReset(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assertNil(t, err)
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"bid": "101.4713", "ask": "103.5213", "spot": "102.4963"}`)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	rate, err := client.FairRate("USD")
+	assertNil(t, err)
+	assertEqual(t, rate.Spot.String(), "102.4963")
+	assertEqual(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	_, err := client.Account()
+	var apiErr *APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.StatusCode, http.StatusServiceUnavailable)
+	assertEqual(t, atomic.LoadInt32(&hits), int32(4))
+}
+
+func TestNoRetryOnNotFound(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, "null")
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	_, err := client.Payment("d4e4fdf8-27bf-4e0f-99dc-13bfe9e55434")
+	assertEqual(t, errors.Is(err, ErrNotFound), true)
+	assertEqual(t, atomic.LoadInt32(&hits), int32(1))
+}
+
+func TestNoRetryWithoutPolicy(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	_, err := client.Account()
+	assertNotNil(t, err)
+	assertEqual(t, atomic.LoadInt32(&hits), int32(1))
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	_, err := client.AccountContext(ctx)
+	assertEqual(t, errors.Is(err, context.DeadlineExceeded), true)
+	assertEqual(t, atomic.LoadInt32(&hits), int32(1))
+}
+
+func TestExponentialBackoffRetry(t *testing.T) {
+	p := NewExponentialBackoff()
+	p.Jitter = 0
+
+	wait, retry := p.Retry(1, errors.New("connection reset by peer"))
+	assertEqual(t, retry, true)
+	assertEqual(t, wait, 500*time.Millisecond)
+
+	wait, retry = p.Retry(3, &APIError{StatusCode: http.StatusBadGateway})
+	assertEqual(t, retry, true)
+	assertEqual(t, wait, 2*time.Second)
+
+	wait, retry = p.Retry(2, &APIError{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"7"}},
+	})
+	assertEqual(t, retry, true)
+	assertEqual(t, wait, 7*time.Second)
+
+	_, retry = p.Retry(1, &APIError{StatusCode: http.StatusBadRequest})
+	assertEqual(t, retry, false)
+
+	_, retry = p.Retry(4, &APIError{StatusCode: http.StatusBadGateway})
+	assertEqual(t, retry, false)
+
+	_, retry = p.Retry(1, context.Canceled)
+	assertEqual(t, retry, false)
+
+	p.MaxAttempts = 100
+	wait, _ = p.Retry(20, errors.New("EOF"))
+	assertEqual(t, wait, 30*time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2014, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("120", now)
+	assertEqual(t, ok, true)
+	assertEqual(t, wait, 2*time.Minute)
+
+	wait, ok = parseRetryAfter("Tue, 01 Apr 2014 12:00:45 GMT", now)
+	assertEqual(t, ok, true)
+	assertEqual(t, wait, 45*time.Second)
+
+	_, ok = parseRetryAfter("", now)
+	assertEqual(t, ok, false)
+
+	_, ok = parseRetryAfter("soon", now)
+	assertEqual(t, ok, false)
+}
+
+func TestNoRetryForPost(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewCustomClient("someapikey", ts.URL)
+	client.SetRetryPolicy(fastBackoff())
+	_, err := client.CreatePayment(PaymentRequest{PayeeName: "ryan@coinjar.io", Amount: MustParseAmount("1.0")})
+	var apiErr *APIError
+	assertEqual(t, errors.As(err, &apiErr), true)
+	assertEqual(t, apiErr.Method, "POST")
+	assertEqual(t, atomic.LoadInt32(&hits), int32(1))
+}
@@ -0,0 +1,187 @@
+package coinjar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeStatus decodes a status string for the status types below. A missing
+// or empty status decodes as unknown, and a known status is matched
+// regardless of case. Any other text is kept as sent.
+func decodeStatus(data []byte, unknown string, known func(string) bool) (string, error) {
+	var text *string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return "", err
+	}
+	if text == nil || *text == "" {
+		return unknown, nil
+	}
+	if v := strings.ToUpper(*text); known(v) {
+		return v, nil
+	}
+	return *text, nil
+}
+
+// PaymentStatus is the state of a Payment. Statuses this package does not
+// know about keep the text the API sent, and IsKnown reports false for them.
+// A missing status decodes as PaymentUnknown.
+type PaymentStatus string
+
+const (
+	PaymentUnknown    PaymentStatus = "UNKNOWN"
+	PaymentPending    PaymentStatus = "PENDING"
+	PaymentProcessing PaymentStatus = "PROCESSING"
+	PaymentCompleted  PaymentStatus = "COMPLETED"
+	PaymentCancelled  PaymentStatus = "CANCELLED"
+	PaymentFailed     PaymentStatus = "FAILED"
+)
+
+func (s PaymentStatus) IsPending() bool {
+	return s == PaymentPending || s == PaymentProcessing
+}
+
+func (s PaymentStatus) IsFinal() bool {
+	return s == PaymentCompleted || s == PaymentCancelled || s == PaymentFailed
+}
+
+func (s *PaymentStatus) UnmarshalJSON(data []byte) error {
+	text, err := decodeStatus(data, string(PaymentUnknown), func(v string) bool {
+		return PaymentStatus(v).IsKnown()
+	})
+	if err != nil {
+		return err
+	}
+	*s = PaymentStatus(text)
+	return nil
+}
+
+// IsKnown reports whether s is one of the statuses defined by this package,
+// other than PaymentUnknown.
+func (s PaymentStatus) IsKnown() bool {
+	switch s {
+	case PaymentPending, PaymentProcessing, PaymentCompleted, PaymentCancelled, PaymentFailed:
+		return true
+	}
+	return false
+}
+
+// TransactionStatus is the state of a Transaction. Statuses this package does
+// not know about keep the text the API sent, and IsKnown reports false for
+// them. A missing status decodes as TransactionUnknown.
+type TransactionStatus string
+
+const (
+	TransactionUnknown     TransactionStatus = "UNKNOWN"
+	TransactionPending     TransactionStatus = "PENDING"
+	TransactionUnconfirmed TransactionStatus = "UNCONFIRMED"
+	TransactionSent        TransactionStatus = "SENT"
+	TransactionReceived    TransactionStatus = "RECEIVED"
+	TransactionCancelled   TransactionStatus = "CANCELLED"
+	TransactionFailed      TransactionStatus = "FAILED"
+)
+
+func (s TransactionStatus) IsPending() bool {
+	return s == TransactionPending || s == TransactionUnconfirmed
+}
+
+func (s TransactionStatus) IsFinal() bool {
+	switch s {
+	case TransactionSent, TransactionReceived, TransactionCancelled, TransactionFailed:
+		return true
+	}
+	return false
+}
+
+func (s *TransactionStatus) UnmarshalJSON(data []byte) error {
+	text, err := decodeStatus(data, string(TransactionUnknown), func(v string) bool {
+		return TransactionStatus(v).IsKnown()
+	})
+	if err != nil {
+		return err
+	}
+	*s = TransactionStatus(text)
+	return nil
+}
+
+// IsKnown reports whether s is one of the statuses defined by this package,
+// other than TransactionUnknown.
+func (s TransactionStatus) IsKnown() bool {
+	switch s {
+	case TransactionPending, TransactionUnconfirmed, TransactionSent, TransactionReceived, TransactionCancelled, TransactionFailed:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes a transaction, accepting confirmations as a number,
+// a numeric string or null.
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type plain Transaction
+	aux := struct {
+		*plain
+		Confirmations json.RawMessage `json:"confirmations"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.Confirmations = 0
+	raw := string(aux.Confirmations)
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		raw = unquoted
+	}
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("coinjar: invalid transaction confirmations %s", aux.Confirmations)
+	}
+	t.Confirmations = n
+	return nil
+}
+
+// OrderStatus is the state of a Checkout Order. Statuses this package does
+// not know about keep the text the API sent, and IsKnown reports false for
+// them. A missing status decodes as OrderUnknown.
+type OrderStatus string
+
+const (
+	OrderUnknown   OrderStatus = "UNKNOWN"
+	OrderUnpaid    OrderStatus = "UNPAID"
+	OrderPending   OrderStatus = "PENDING"
+	OrderCompleted OrderStatus = "COMPLETED"
+	OrderCancelled OrderStatus = "CANCELLED"
+	OrderExpired   OrderStatus = "EXPIRED"
+)
+
+func (s OrderStatus) IsPending() bool {
+	return s == OrderUnpaid || s == OrderPending
+}
+
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderCompleted || s == OrderCancelled || s == OrderExpired
+}
+
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	text, err := decodeStatus(data, string(OrderUnknown), func(v string) bool {
+		return OrderStatus(v).IsKnown()
+	})
+	if err != nil {
+		return err
+	}
+	*s = OrderStatus(text)
+	return nil
+}
+
+// IsKnown reports whether s is one of the statuses defined by this package,
+// other than OrderUnknown.
+func (s OrderStatus) IsKnown() bool {
+	switch s {
+	case OrderUnpaid, OrderPending, OrderCompleted, OrderCancelled, OrderExpired:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package coinjar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPaymentStatus(t *testing.T) {
+	var v struct{ A, B, C, D PaymentStatus }
+	err := json.Unmarshal([]byte(`{"a": "COMPLETED", "b": "pending", "c": "REFUNDED", "d": null}`), &v)
+	assertNil(t, err)
+	assertEqual(t, v.A, PaymentCompleted)
+	assertEqual(t, v.B, PaymentPending)
+	assertEqual(t, v.C, PaymentStatus("REFUNDED"))
+	assertEqual(t, v.C.IsKnown(), false)
+	assertEqual(t, v.D, PaymentUnknown)
+	assertEqual(t, v.A.IsKnown(), true)
+	assertEqual(t, PaymentUnknown.IsKnown(), false)
+
+	out, err := json.Marshal(v)
+	assertNil(t, err)
+	assertEqual(t, string(out), `{"A":"COMPLETED","B":"PENDING","C":"REFUNDED","D":"UNKNOWN"}`)
+
+	assertEqual(t, PaymentCompleted.IsFinal(), true)
+	assertEqual(t, PaymentCompleted.IsPending(), false)
+	assertEqual(t, PaymentProcessing.IsPending(), true)
+	assertEqual(t, PaymentCancelled.IsFinal(), true)
+	assertEqual(t, PaymentUnknown.IsFinal(), false)
+	assertEqual(t, PaymentUnknown.IsPending(), false)
+}
+
+func TestTransactionStatus(t *testing.T) {
+	var v struct{ A, B TransactionStatus }
+	err := json.Unmarshal([]byte(`{"a": "RECEIVED", "b": "REVERSED"}`), &v)
+	assertNil(t, err)
+	assertEqual(t, v.A, TransactionReceived)
+	assertEqual(t, v.B, TransactionStatus("REVERSED"))
+	assertEqual(t, v.B.IsKnown(), false)
+
+	assertEqual(t, TransactionSent.IsFinal(), true)
+	assertEqual(t, TransactionUnconfirmed.IsPending(), true)
+	assertEqual(t, TransactionUnconfirmed.IsFinal(), false)
+	assertEqual(t, TransactionUnknown.IsPending(), false)
+
+	assertNotNil(t, json.Unmarshal([]byte(`{"a": 3}`), &v))
+}
+
+func TestOrderStatus(t *testing.T) {
+	var v struct{ A, B OrderStatus }
+	err := json.Unmarshal([]byte(`{"a": "expired", "b": "Refunded"}`), &v)
+	assertNil(t, err)
+	assertEqual(t, v.A, OrderExpired)
+	assertEqual(t, v.B, OrderStatus("Refunded"))
+	assertEqual(t, v.B.IsKnown(), false)
+
+	assertEqual(t, OrderUnpaid.IsPending(), true)
+	assertEqual(t, OrderCompleted.IsFinal(), true)
+	assertEqual(t, OrderUnknown.IsFinal(), false)
+}
+
+func TestTransactionConfirmations(t *testing.T) {
+	for in, want := range map[string]int{
+		`{"confirmations": 6}`:    6,
+		`{"confirmations": "12"}`: 12,
+		`{"confirmations": null}`: 0,
+		`{"confirmations": ""}`:   0,
+		`{}`:                      0,
+	} {
+		tx := Transaction{Confirmations: 99}
+		assertNil(t, json.Unmarshal([]byte(in), &tx))
+		assertEqual(t, tx.Confirmations, want)
+	}
+
+	var tx Transaction
+	err := json.Unmarshal([]byte(`{"uuid": "3eb68998", "status": "SENT", "amount": "-0.01", "confirmations": "many"}`), &tx)
+	assertEqual(t, err.Error(), `coinjar: invalid transaction confirmations "many"`)
+
+	err = json.Unmarshal([]byte(`{"uuid": "3eb68998", "status": "SENT", "amount": "-0.01", "confirmations": "3"}`), &tx)
+	assertNil(t, err)
+	assertEqual(t, tx.UUID, "3eb68998")
+	assertEqual(t, tx.Status, TransactionSent)
+	assertEqual(t, tx.Amount.String(), "-0.01")
+	assertEqual(t, tx.Confirmations, 3)
+}
@@ -0,0 +1,307 @@
+package coinjar
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateUpdate is a fair rate that differs from the last one published for its
+// currency. Previous is nil for the first rate seen.
+type RateUpdate struct {
+	Currency string
+	Rate     FairRate
+	Previous *FairRate
+	Time     time.Time
+}
+
+// RateTickerOptions configures a RateTicker. The zero value polls every
+// DefaultWatchInterval.
+type RateTickerOptions struct {
+	Interval time.Duration
+	// MaxBackoff caps the delay between polls after consecutive errors, as
+	// for WatchOptions. Defaults to ten times Interval.
+	MaxBackoff time.Duration
+	// OnError is called with every failed fetch. Errors are also logged.
+	OnError func(error)
+}
+
+type conditionKind int
+
+const (
+	spotAbove conditionKind = iota
+	spotBelow
+	spreadAbove
+	percentChange
+)
+
+// RateCondition is a threshold a RateTicker alert watches for. Build one with
+// SpotAbove, SpotBelow, SpreadAbove or PercentChange.
+type RateCondition struct {
+	Currency  string
+	kind      conditionKind
+	threshold Amount
+	percent   float64
+	window    time.Duration
+}
+
+// SpotAbove holds while the spot price is above price.
+func SpotAbove(currency string, price Amount) RateCondition {
+	return RateCondition{Currency: strings.ToUpper(currency), kind: spotAbove, threshold: price}
+}
+
+// SpotBelow holds while the spot price is below price.
+func SpotBelow(currency string, price Amount) RateCondition {
+	return RateCondition{Currency: strings.ToUpper(currency), kind: spotBelow, threshold: price}
+}
+
+// SpreadAbove holds while the ask exceeds the bid by more than spread.
+func SpreadAbove(currency string, spread Amount) RateCondition {
+	return RateCondition{Currency: strings.ToUpper(currency), kind: spreadAbove, threshold: spread}
+}
+
+// PercentChange holds while the spot price has moved, up or down, by at least
+// percent since the start of the trailing window.
+func PercentChange(currency string, percent float64, window time.Duration) RateCondition {
+	return RateCondition{Currency: strings.ToUpper(currency), kind: percentChange, percent: math.Abs(percent), window: window}
+}
+
+func (c RateCondition) String() string {
+	switch c.kind {
+	case spotAbove:
+		return fmt.Sprintf("%s spot above %s", c.Currency, c.threshold)
+	case spotBelow:
+		return fmt.Sprintf("%s spot below %s", c.Currency, c.threshold)
+	case spreadAbove:
+		return fmt.Sprintf("%s spread above %s", c.Currency, c.threshold)
+	}
+	return fmt.Sprintf("%s spot moved %g%% within %v", c.Currency, c.percent, c.window)
+}
+
+// RateAlert is sent when a condition starts to hold. Change is the percentage
+// move for PercentChange conditions.
+type RateAlert struct {
+	Condition RateCondition
+	Rate      FairRate
+	Change    float64
+	Time      time.Time
+}
+
+type rateSample struct {
+	time time.Time
+	spot Amount
+}
+
+type rateAlert struct {
+	cond   RateCondition
+	fn     func(RateAlert)
+	firing bool
+}
+
+// RateTicker polls the fair rate of one or more currencies, publishes each
+// rate that changed and fires alerts registered with Alert.
+type RateTicker struct {
+	client     *Client
+	currencies []string
+	opts       RateTickerOptions
+	now        func() time.Time
+
+	mu      sync.Mutex
+	alerts  []*rateAlert
+	last    map[string]FairRate
+	history map[string][]rateSample
+}
+
+// NewRateTicker returns a ticker for currencies. opts may be nil.
+func (c *Client) NewRateTicker(currencies []string, opts *RateTickerOptions) *RateTicker {
+	t := &RateTicker{
+		client:  c,
+		now:     time.Now,
+		last:    make(map[string]FairRate),
+		history: make(map[string][]rateSample),
+	}
+	for _, currency := range currencies {
+		t.currencies = append(t.currencies, strings.ToUpper(currency))
+	}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Interval <= 0 {
+		t.opts.Interval = DefaultWatchInterval
+	}
+	if t.opts.MaxBackoff <= 0 {
+		t.opts.MaxBackoff = 10 * t.opts.Interval
+	}
+	return t
+}
+
+// Alert calls fn each time cond starts to hold: when it holds for the first
+// rate checked, and again after it has stopped holding. The returned func
+// removes the alert. Alert is safe to call while the ticker runs.
+func (t *RateTicker) Alert(cond RateCondition, fn func(RateAlert)) (remove func()) {
+	a := &rateAlert{cond: cond, fn: fn}
+	t.mu.Lock()
+	t.alerts = append(t.alerts, a)
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for i, other := range t.alerts {
+			if other == a {
+				t.alerts = append(t.alerts[:i], t.alerts[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Run polls until ctx is done, calling fn with each changed rate. It polls
+// once immediately and returns ctx.Err().
+func (t *RateTicker) Run(ctx context.Context, fn func(RateUpdate)) error {
+	failures := 0
+	for {
+		failed := false
+		for _, currency := range t.currencies {
+			rate, err := t.client.FairRateContext(ctx, currency)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				t.client.logf("coinjar: rate ticker %s failed: %v", currency, err)
+				if t.opts.OnError != nil {
+					t.opts.OnError(err)
+				}
+				failed = true
+				continue
+			}
+			if update, ok := t.observe(currency, *rate); ok {
+				fn(update)
+			}
+		}
+		delay := t.opts.Interval
+		if failed {
+			failures++
+			delay = backoffDelay(t.opts.Interval, t.opts.MaxBackoff, failures)
+		} else {
+			failures = 0
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Updates runs the ticker in a goroutine and delivers changed rates on the
+// returned channel, which is closed once ctx is done.
+func (t *RateTicker) Updates(ctx context.Context) <-chan RateUpdate {
+	ch := make(chan RateUpdate)
+	go func() {
+		defer close(ch)
+		t.Run(ctx, func(u RateUpdate) {
+			select {
+			case ch <- u:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return ch
+}
+
+// observe records a fetched rate, fires alerts and reports whether the rate
+// differs from the last one published.
+func (t *RateTicker) observe(currency string, rate FairRate) (RateUpdate, bool) {
+	now := t.now()
+	t.mu.Lock()
+	prev, seen := t.last[currency]
+	changed := !seen || !prev.Bid.Equal(rate.Bid) || !prev.Ask.Equal(rate.Ask) || !prev.Spot.Equal(rate.Spot)
+	if changed {
+		t.last[currency] = rate
+		t.history[currency] = append(t.history[currency], rateSample{now, rate.Spot})
+	}
+	var fire []RateAlert
+	var fns []func(RateAlert)
+	for _, a := range t.alerts {
+		if a.cond.Currency != currency {
+			continue
+		}
+		holds, change := t.check(a.cond, rate, now)
+		if holds && !a.firing {
+			fire = append(fire, RateAlert{Condition: a.cond, Rate: rate, Change: change, Time: now})
+			fns = append(fns, a.fn)
+		}
+		a.firing = holds
+	}
+	t.trim(currency, now)
+	t.mu.Unlock()
+
+	for i, alert := range fire {
+		fns[i](alert)
+	}
+	update := RateUpdate{Currency: currency, Rate: rate, Time: now}
+	if seen {
+		update.Previous = &prev
+	}
+	return update, changed
+}
+
+func (t *RateTicker) check(c RateCondition, rate FairRate, now time.Time) (bool, float64) {
+	switch c.kind {
+	case spotAbove:
+		return rate.Spot.Cmp(c.threshold) > 0, 0
+	case spotBelow:
+		return rate.Spot.Cmp(c.threshold) < 0, 0
+	case spreadAbove:
+		return rate.Ask.Sub(rate.Bid).Cmp(c.threshold) > 0, 0
+	}
+	base, ok := t.spotAt(c.Currency, now.Add(-c.window))
+	if !ok || base.Sign() <= 0 {
+		return false, 0
+	}
+	change := toFloat(rate.Spot.Sub(base).Quo(base)) * 100
+	return math.Abs(change) >= c.percent, change
+}
+
+// spotAt returns the spot price in effect at time at: the last sample taken
+// at or before it, or the oldest sample if history does not reach back that
+// far.
+func (t *RateTicker) spotAt(currency string, at time.Time) (Amount, bool) {
+	samples := t.history[currency]
+	if len(samples) == 0 {
+		return Amount{}, false
+	}
+	spot := samples[0].spot
+	for _, s := range samples {
+		if s.time.After(at) {
+			break
+		}
+		spot = s.spot
+	}
+	return spot, true
+}
+
+// trim drops samples no PercentChange window can reach, keeping the last one
+// before the longest window so spotAt still knows the price at its start.
+func (t *RateTicker) trim(currency string, now time.Time) {
+	var window time.Duration
+	for _, a := range t.alerts {
+		if a.cond.Currency == currency && a.cond.kind == percentChange && a.cond.window > window {
+			window = a.cond.window
+		}
+	}
+	samples := t.history[currency]
+	cutoff := now.Add(-window)
+	i := 0
+	for i+1 < len(samples) && !samples[i+1].time.After(cutoff) {
+		i++
+	}
+	t.history[currency] = samples[i:]
+}
+
+func toFloat(a Amount) float64 {
+	f, _ := strconv.ParseFloat(a.String(), 64)
+	return f
+}
@@ -0,0 +1,132 @@
+package coinjar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rateServer serves the current spot, bid and ask for each currency.
+type rateServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	rates map[string]string
+}
+
+func newRateServer(t *testing.T) *rateServer {
+	s := &rateServer{rates: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		currency := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/fair_rate/"), ".json")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		body, ok := s.rates[currency]
+		if !ok {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"error": "Not Found"}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	return s
+}
+
+func (s *rateServer) set(currency, bid, ask, spot string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[currency] = fmt.Sprintf(`{"bid": "%s", "ask": "%s", "spot": "%s"}`, bid, ask, spot)
+}
+
+func rate(bid, ask, spot string) FairRate {
+	return FairRate{Bid: MustParseAmount(bid), Ask: MustParseAmount(ask), Spot: MustParseAmount(spot)}
+}
+
+func TestRateTickerDeduplicates(t *testing.T) {
+	ts := newRateServer(t)
+	defer ts.Close()
+	ts.set("USD", "99", "101", "100")
+	ts.set("AUD", "109", "111", "110")
+
+	ticker := NewCustomClient("someapikey", ts.URL).NewRateTicker([]string{"usd", "aud"}, &RateTickerOptions{Interval: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := ticker.Updates(ctx)
+
+	first, second := <-updates, <-updates
+	assertEqual(t, first.Currency, "USD")
+	assertEqual(t, first.Rate.Spot.String(), "100.0")
+	assertEqual(t, first.Previous == nil, true)
+	assertEqual(t, second.Currency, "AUD")
+
+	time.Sleep(20 * time.Millisecond)
+	ts.set("USD", "100", "102", "101")
+	next := <-updates
+	assertEqual(t, next.Currency, "USD")
+	assertEqual(t, next.Rate.Spot.String(), "101.0")
+	assertEqual(t, next.Previous.Spot.String(), "100.0")
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestRateTickerThresholdAlerts(t *testing.T) {
+	ticker := NewCustomClient("someapikey", "http://unused").NewRateTicker([]string{"USD"}, nil)
+	var alerts []string
+	record := func(a RateAlert) { alerts = append(alerts, a.Condition.String()) }
+	ticker.Alert(SpotAbove("usd", MustParseAmount("105")), record)
+	ticker.Alert(SpotBelow("USD", MustParseAmount("95")), record)
+	remove := ticker.Alert(SpreadAbove("USD", MustParseAmount("5")), record)
+	ticker.Alert(SpotAbove("AUD", MustParseAmount("1")), record)
+
+	for _, r := range []FairRate{
+		rate("99", "101", "100"),
+		rate("105", "107", "106"),
+		rate("106", "108", "107"), // still above, no repeat
+		rate("99", "101", "100"),
+		rate("104", "112", "108"),
+		rate("90", "92", "91"),
+	} {
+		ticker.observe("USD", r)
+	}
+	assertEqual(t, fmt.Sprint(alerts), "[USD spot above 105.0 USD spot above 105.0 USD spread above 5.0 USD spot below 95.0]")
+
+	remove()
+	alerts = nil
+	ticker.observe("USD", rate("100", "110", "105"))
+	assertEqual(t, len(alerts), 0)
+}
+
+func TestRateTickerPercentChange(t *testing.T) {
+	ticker := NewCustomClient("someapikey", "http://unused").NewRateTicker([]string{"USD"}, nil)
+	now := time.Date(2014, 4, 1, 0, 0, 0, 0, time.UTC)
+	ticker.now = func() time.Time { return now }
+	var alerts []RateAlert
+	ticker.Alert(PercentChange("USD", 5, time.Hour), func(a RateAlert) { alerts = append(alerts, a) })
+
+	step := func(d time.Duration, spot string) {
+		now = now.Add(d)
+		ticker.observe("USD", rate(spot, spot, spot))
+	}
+	step(0, "100")
+	step(20*time.Minute, "103")
+	step(20*time.Minute, "104")
+	assertEqual(t, len(alerts), 0)
+
+	step(10*time.Minute, "106")
+	assertEqual(t, len(alerts), 1)
+	assertEqual(t, alerts[0].Change, 6.0)
+
+	// An hour on, the move is measured from 103 and no longer holds.
+	step(30*time.Minute, "106")
+	step(0, "106.5")
+	assertEqual(t, len(alerts), 1)
+
+	step(10*time.Minute, "97")
+	assertEqual(t, len(alerts), 2)
+	assertEqual(t, alerts[1].Change < -5, true)
+}
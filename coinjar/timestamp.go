@@ -0,0 +1,83 @@
+package coinjar
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// timestampLayouts are the formats the API has been seen to use, tried in
+// order.
+var timestampLayouts = []string{
+	time.RFC3339Nano,                      // 2013-06-25T17:58:17.000+10:00
+	"2006-01-02T15:04:05.999999999-0700",  // 2013-06-25T17:58:17.000+1000
+	"2006-01-02 15:04:05.999999999 -0700", // 2013-06-25 17:58:17 +1000
+	"2006-01-02 15:04:05.999999999 MST",   // 2013-06-25 17:58:17 UTC
+}
+
+const apiTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Timestamp is a time decoded from the API. Raw keeps the text as it was sent.
+type Timestamp struct {
+	time.Time
+	Raw string
+}
+
+func ParseTimestamp(s string) (Timestamp, error) {
+	for _, layout := range timestampLayouts {
+		// Parsing in UTC keeps the result from depending on the local zone.
+		t, err := time.ParseInLocation(layout, s, time.UTC)
+		if err != nil {
+			continue
+		}
+		// A zone abbreviation other than UTC, such as AEST, is given a zero
+		// offset rather than an error.
+		if name, offset := t.Zone(); offset == 0 && name != "UTC" && name != "GMT" {
+			return Timestamp{}, fmt.Errorf("coinjar: cannot parse timestamp %q, unknown time zone %q", s, name)
+		}
+		return Timestamp{Time: t, Raw: s}, nil
+	}
+	return Timestamp{}, fmt.Errorf("coinjar: cannot parse timestamp %q, expected a format like %q", s, apiTimestampLayout)
+}
+
+// String returns the timestamp as the API sent it, or formatted the same way
+// if it was built locally.
+func (t Timestamp) String() string {
+	if t.Raw != "" {
+		return t.Raw
+	}
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(apiTimestampLayout)
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.Raw == "" && t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.Quote(t.String())), nil
+}
+
+// UnmarshalJSON accepts a timestamp string, or null and "" which decode to
+// the zero Timestamp.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("coinjar: timestamp must be a string, got %s", data)
+	}
+	if s == "" {
+		*t = Timestamp{}
+		return nil
+	}
+	parsed, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
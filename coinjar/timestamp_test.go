@@ -0,0 +1,57 @@
+package coinjar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2013, 6, 25, 7, 58, 17, 0, time.UTC)
+	for _, in := range []string{
+		"2013-06-25T17:58:17.000+10:00",
+		"2013-06-25T17:58:17+10:00",
+		"2013-06-25T07:58:17Z",
+		"2013-06-25T17:58:17.000+1000",
+		"2013-06-25 17:58:17 +1000",
+		"2013-06-25 07:58:17 UTC",
+	} {
+		ts, err := ParseTimestamp(in)
+		assertNil(t, err)
+		assertEqual(t, ts.UTC(), want)
+		assertEqual(t, ts.Raw, in)
+		assertEqual(t, ts.String(), in)
+	}
+
+	_, err := ParseTimestamp("25/06/2013")
+	assertEqual(t, err.Error(), `coinjar: cannot parse timestamp "25/06/2013", expected a format like "2006-01-02T15:04:05.000Z07:00"`)
+
+	_, err = ParseTimestamp("2013-06-25 17:58:17 AEST")
+	assertEqual(t, err.Error(), `coinjar: cannot parse timestamp "2013-06-25 17:58:17 AEST", unknown time zone "AEST"`)
+	ts, err := ParseTimestamp("2013-06-25 07:58:17 GMT")
+	assertNil(t, err)
+	assertEqual(t, ts.UTC(), want)
+}
+
+func TestTimestampJSON(t *testing.T) {
+	var v struct {
+		A Timestamp
+		B Timestamp
+		C Timestamp
+	}
+	err := json.Unmarshal([]byte(`{"a": "2013-05-14T14:17:05.000+10:00", "b": null, "c": ""}`), &v)
+	assertNil(t, err)
+	assertEqual(t, v.A.UTC(), time.Date(2013, 5, 14, 4, 17, 5, 0, time.UTC))
+	assertEqual(t, v.B.IsZero(), true)
+	assertEqual(t, v.C.IsZero(), true)
+
+	out, err := json.Marshal(v)
+	assertNil(t, err)
+	assertEqual(t, string(out), `{"A":"2013-05-14T14:17:05.000+10:00","B":null,"C":null}`)
+
+	local := Timestamp{Time: time.Date(2014, 4, 1, 0, 0, 0, 0, time.UTC)}
+	assertEqual(t, local.String(), "2014-04-01T00:00:00.000Z")
+
+	assertNotNil(t, json.Unmarshal([]byte(`{"a": "yesterday"}`), &v))
+	assertNotNil(t, json.Unmarshal([]byte(`{"a": 1371000000}`), &v))
+}
@@ -0,0 +1,241 @@
+package coinjar
+
+import (
+	"context"
+	"time"
+)
+
+const DefaultWatchInterval = 30 * time.Second
+
+type EventType int
+
+const (
+	// TransactionNew is sent the first time a transaction is seen.
+	TransactionNew EventType = iota
+	// TransactionStatusChanged is sent when a seen transaction's status
+	// differs from the last poll.
+	TransactionStatusChanged
+	// TransactionConfirmationsIncreased is sent when a seen transaction has
+	// more confirmations than at the last poll.
+	TransactionConfirmationsIncreased
+	// TransactionConfirmed is sent once, when a transaction first has at
+	// least WatchOptions.Confirmations confirmations.
+	TransactionConfirmed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case TransactionNew:
+		return "new"
+	case TransactionStatusChanged:
+		return "status changed"
+	case TransactionConfirmationsIncreased:
+		return "confirmations increased"
+	case TransactionConfirmed:
+		return "confirmed"
+	}
+	return "unknown"
+}
+
+// TransactionEvent describes a change seen by a Watcher. Previous is the
+// transaction as of the last poll, nil for TransactionNew.
+type TransactionEvent struct {
+	Type        EventType
+	Transaction Transaction
+	Previous    *Transaction
+}
+
+// WatchOptions configures a Watcher. The zero value watches the latest
+// DefaultPageSize transactions, polling every DefaultWatchInterval.
+type WatchOptions struct {
+	Interval time.Duration
+	// Limit is the number of most recent transactions watched for changes,
+	// and the page size used to fetch them. It is at most MaxPageSize.
+	Limit int
+	// NewestFirst says the API lists transactions newest first, as for
+	// coinjarsync.Options. A poll then fetches a single page from offset 0.
+	// Otherwise it pages from the start of the last window to the end of the
+	// list, so the first poll reads every transaction.
+	NewestFirst bool
+	// Confirmations is the count that triggers TransactionConfirmed. Zero
+	// disables the event.
+	Confirmations int
+	// MaxBackoff caps the delay between polls after consecutive errors. The
+	// delay doubles from Interval on each error. Defaults to ten times
+	// Interval.
+	MaxBackoff time.Duration
+	// EmitExisting sends TransactionNew for the transactions found by the
+	// first poll. By default they only form the baseline later polls are
+	// compared against.
+	EmitExisting bool
+	// OnError is called with every failed poll. Errors are also logged.
+	OnError func(error)
+}
+
+// Watcher polls the account's transactions and reports what changed between
+// polls.
+type Watcher struct {
+	client *Client
+	opts   WatchOptions
+	seen   map[string]Transaction
+	primed bool
+	// offset is where the window of watched transactions starts when they
+	// are listed oldest first.
+	offset int
+}
+
+// NewWatcher returns a Watcher for the client's account. opts may be nil.
+func (c *Client) NewWatcher(opts *WatchOptions) *Watcher {
+	w := &Watcher{client: c, seen: make(map[string]Transaction)}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Interval <= 0 {
+		w.opts.Interval = DefaultWatchInterval
+	}
+	if w.opts.Limit <= 0 {
+		w.opts.Limit = DefaultPageSize
+	}
+	if w.opts.Limit > MaxPageSize {
+		w.opts.Limit = MaxPageSize
+	}
+	if w.opts.MaxBackoff <= 0 {
+		w.opts.MaxBackoff = 10 * w.opts.Interval
+	}
+	return w
+}
+
+// Run polls until ctx is done, calling fn with each event in the order found.
+// It polls once immediately and returns ctx.Err().
+func (w *Watcher) Run(ctx context.Context, fn func(TransactionEvent)) error {
+	failures := 0
+	for {
+		events, err := w.poll(ctx)
+		delay := w.opts.Interval
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			w.client.logf("coinjar: watcher poll failed: %v", err)
+			if w.opts.OnError != nil {
+				w.opts.OnError(err)
+			}
+			failures++
+			delay = backoffDelay(w.opts.Interval, w.opts.MaxBackoff, failures)
+		} else {
+			failures = 0
+			for _, e := range events {
+				fn(e)
+			}
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Watch runs the watcher in a goroutine and delivers events on the returned
+// channel, which is closed once ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan TransactionEvent {
+	ch := make(chan TransactionEvent)
+	go func() {
+		defer close(ch)
+		w.Run(ctx, func(e TransactionEvent) {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return ch
+}
+
+// poll fetches the latest transactions and diffs them against the last poll.
+// Only the newest Limit transactions are remembered for the next one.
+func (w *Watcher) poll(ctx context.Context) ([]TransactionEvent, error) {
+	transactions, window, err := w.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var events []TransactionEvent
+	for _, t := range transactions {
+		prev, ok := w.seen[t.UUID]
+		w.seen[t.UUID] = t
+		if !ok {
+			if !w.primed && !w.opts.EmitExisting {
+				continue
+			}
+			events = append(events, TransactionEvent{Type: TransactionNew, Transaction: t})
+			if w.opts.Confirmations > 0 && t.Confirmations >= w.opts.Confirmations {
+				events = append(events, TransactionEvent{Type: TransactionConfirmed, Transaction: t})
+			}
+			continue
+		}
+		previous := prev
+		if t.Status != prev.Status {
+			events = append(events, TransactionEvent{Type: TransactionStatusChanged, Transaction: t, Previous: &previous})
+		}
+		if t.Confirmations > prev.Confirmations {
+			events = append(events, TransactionEvent{Type: TransactionConfirmationsIncreased, Transaction: t, Previous: &previous})
+			if w.opts.Confirmations > 0 && prev.Confirmations < w.opts.Confirmations && t.Confirmations >= w.opts.Confirmations {
+				events = append(events, TransactionEvent{Type: TransactionConfirmed, Transaction: t, Previous: &previous})
+			}
+		}
+	}
+	w.primed = true
+
+	keep := make(map[string]bool, len(window))
+	for _, t := range window {
+		keep[t.UUID] = true
+	}
+	for uuid := range w.seen {
+		if !keep[uuid] {
+			delete(w.seen, uuid)
+		}
+	}
+	return events, nil
+}
+
+// fetch returns the transactions to diff, and the newest Limit of them that
+// form the next window.
+func (w *Watcher) fetch(ctx context.Context) (transactions, window []Transaction, err error) {
+	if w.opts.NewestFirst {
+		transactions, err = w.client.ListTransactionsContext(ctx, w.opts.Limit, 0)
+		return transactions, transactions, err
+	}
+	offset := w.offset
+	for {
+		page, err := w.client.ListTransactionsContext(ctx, w.opts.Limit, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		transactions = append(transactions, page...)
+		offset += len(page)
+		if len(page) < w.opts.Limit {
+			break
+		}
+	}
+	if len(transactions) == 0 && w.offset > 0 {
+		// The list shrank past the window; start again from the top.
+		w.offset = 0
+		return w.fetch(ctx)
+	}
+	window = transactions
+	if len(window) > w.opts.Limit {
+		window = window[len(window)-w.opts.Limit:]
+	}
+	w.offset = offset - len(window)
+	return transactions, window, nil
+}
+
+// backoffDelay doubles interval once per consecutive failure, up to max.
+func backoffDelay(interval, max time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 0; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,191 @@
+package coinjar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// transactionsServer serves whatever transactions document is current.
+type transactionsServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	body   string
+	status int
+}
+
+func newTransactionsServer(t *testing.T) *transactionsServer {
+	s := &transactionsServer{body: `{"transactions": []}`, status: 200}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequestUsesApiKey(t, r, "someapikey")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.WriteHeader(s.status)
+		fmt.Fprint(w, s.body)
+	}))
+	return s
+}
+
+func (s *transactionsServer) set(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+func eventTypes(events []TransactionEvent) string {
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Transaction.UUID+" "+e.Type.String())
+	}
+	return fmt.Sprint(types)
+}
+
+func TestWatcherDiff(t *testing.T) {
+	ts := newTransactionsServer(t)
+	defer ts.Close()
+	w := NewCustomClient("someapikey", ts.URL).NewWatcher(&WatchOptions{Confirmations: 3})
+	ctx := context.Background()
+
+	ts.set(200, `{"transactions": [{"uuid": "a", "status": "RECEIVED", "confirmations": 10}]}`)
+	events, err := w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(events), 0)
+
+	ts.set(200, `{"transactions": [
+		{"uuid": "a", "status": "RECEIVED", "confirmations": 11},
+		{"uuid": "b", "status": "UNCONFIRMED", "confirmations": 0}
+	]}`)
+	events, err = w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, eventTypes(events), "[a confirmations increased b new]")
+
+	ts.set(200, `{"transactions": [
+		{"uuid": "a", "status": "RECEIVED", "confirmations": 11},
+		{"uuid": "b", "status": "RECEIVED", "confirmations": 4}
+	]}`)
+	events, err = w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, eventTypes(events), "[b status changed b confirmations increased b confirmed]")
+	assertEqual(t, events[0].Previous.Status, TransactionUnconfirmed)
+	assertEqual(t, events[0].Transaction.Status, TransactionReceived)
+
+	ts.set(200, `{"transactions": [{"uuid": "b", "status": "RECEIVED", "confirmations": 5}]}`)
+	events, err = w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, eventTypes(events), "[b confirmations increased]")
+}
+
+func TestWatcherOldestFirst(t *testing.T) {
+	var mu sync.Mutex
+	var offsets []string
+	transactions := []string{
+		`{"uuid": "a", "status": "RECEIVED"}`,
+		`{"uuid": "b", "status": "RECEIVED"}`,
+		`{"uuid": "c", "status": "RECEIVED"}`,
+		`{"uuid": "d", "status": "UNCONFIRMED"}`,
+		`{"uuid": "e", "status": "UNCONFIRMED"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		var page []string
+		for i := offset; i < len(transactions) && i < offset+limit; i++ {
+			page = append(page, transactions[i])
+		}
+		fmt.Fprintf(w, `{"transactions": [%s]}`, strings.Join(page, ","))
+	}))
+	defer ts.Close()
+	w := NewCustomClient("someapikey", ts.URL).NewWatcher(&WatchOptions{Limit: 2})
+	ctx := context.Background()
+
+	events, err := w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, len(events), 0)
+	assertEqual(t, fmt.Sprint(offsets), "[0 2 4]")
+	assertEqual(t, len(w.seen), 2)
+
+	mu.Lock()
+	offsets = nil
+	transactions[4] = `{"uuid": "e", "status": "RECEIVED"}`
+	transactions = append(transactions, `{"uuid": "f", "status": "UNCONFIRMED"}`, `{"uuid": "g", "status": "UNCONFIRMED"}`)
+	mu.Unlock()
+	events, err = w.poll(ctx)
+	assertNil(t, err)
+	assertEqual(t, eventTypes(events), "[e status changed f new g new]")
+	assertEqual(t, fmt.Sprint(offsets), "[3 5 7]")
+	assertEqual(t, len(w.seen), 2)
+	assertEqual(t, w.seen["g"].Status, TransactionUnconfirmed)
+}
+
+func TestWatcherEmitExisting(t *testing.T) {
+	ts := newTransactionsServer(t)
+	defer ts.Close()
+	ts.set(200, `{"transactions": [{"uuid": "a", "status": "RECEIVED", "confirmations": 6}]}`)
+	w := NewCustomClient("someapikey", ts.URL).NewWatcher(&WatchOptions{Confirmations: 6, EmitExisting: true})
+
+	events, err := w.poll(context.Background())
+	assertNil(t, err)
+	assertEqual(t, eventTypes(events), "[a new a confirmed]")
+}
+
+func TestWatcherChannel(t *testing.T) {
+	ts := newTransactionsServer(t)
+	defer ts.Close()
+	w := NewCustomClient("someapikey", ts.URL).NewWatcher(&WatchOptions{Interval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := w.Watch(ctx)
+	time.Sleep(20 * time.Millisecond)
+	ts.set(200, `{"transactions": [{"uuid": "a", "status": "UNCONFIRMED"}]}`)
+
+	select {
+	case e := <-events:
+		assertEqual(t, e.Type, TransactionNew)
+		assertEqual(t, e.Transaction.UUID, "a")
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatcherBacksOffOnError(t *testing.T) {
+	ts := newTransactionsServer(t)
+	defer ts.Close()
+	ts.set(503, `{"error": "Service Unavailable"}`)
+
+	var mu sync.Mutex
+	var failures []time.Time
+	w := NewCustomClient("someapikey", ts.URL).NewWatcher(&WatchOptions{
+		Interval:   10 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			failures = append(failures, time.Now())
+			mu.Unlock()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	err := w.Run(ctx, func(TransactionEvent) { t.Error("unexpected event") })
+	assertEqual(t, err, context.DeadlineExceeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Delays of 20ms, 40ms, 40ms... fit at most five polls into 150ms, where
+	// a fixed 10ms interval would fit fifteen.
+	assertEqual(t, len(failures) >= 2 && len(failures) <= 6, true)
+	assertEqual(t, failures[1].Sub(failures[0]) >= 20*time.Millisecond, true)
+}
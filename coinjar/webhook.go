@@ -0,0 +1,211 @@
+package coinjar
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The API documentation does not name the notification headers, so these are
+// only defaults. Set WebhookHandler.SignatureHeader and SecretHeader to match
+// the headers your notifications actually carry.
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of a notification body,
+	// keyed with the webhook secret and optionally prefixed with "sha256=".
+	SignatureHeader = "X-CoinJar-Signature"
+	// SecretHeader carries the webhook secret itself, for notify URLs that
+	// cannot be signed. The secret may also be sent as the "secret" query
+	// parameter of the notify URL.
+	SecretHeader = "X-CoinJar-Secret"
+)
+
+// maxWebhookBody bounds the notification bodies a WebhookHandler reads.
+const maxWebhookBody = 1 << 20
+
+// DefaultWebhookMemory is the number of handled notifications a
+// WebhookHandler remembers to recognise redeliveries.
+const DefaultWebhookMemory = 10000
+
+// WebhookHandler receives payment and transaction notifications. Each is
+// POSTed as JSON in the same envelope the API uses, {"payment": {...}} or
+// {"transaction": {...}}, and must be signed with, or carry, the shared
+// secret.
+//
+// Notifications are deduplicated by UUID. One for a UUID already handled is
+// a redelivery, acknowledged without calling the callback again, unless the
+// record has moved on since: its status or updated_at differ, or for a
+// transaction its confirmation count. Those changes are dispatched, so that
+// callers hear about a payment completing or a transaction confirming.
+//
+// The handler replies 200 once a notification is handled or recognised as a
+// redelivery. It replies 401 if the notification is not signed or the secret
+// is wrong, 400 if it cannot be decoded and 405 if it is not a POST. If the
+// callback returns an error it replies 500, so CoinJar delivers the
+// notification again.
+//
+//	handler := coinjar.NewWebhookHandler(secret)
+//	handler.OnPayment = func(ctx context.Context, p coinjar.Payment) error {
+//		return orders.MarkPaid(ctx, p.Reference)
+//	}
+//	http.Handle("/coinjar/notify", handler)
+type WebhookHandler struct {
+	// OnPayment and OnTransaction are called with decoded notifications.
+	// Notifications without a callback are acknowledged and dropped.
+	OnPayment     func(context.Context, Payment) error
+	OnTransaction func(context.Context, Transaction) error
+	// Memory caps how many handled notifications are remembered. Defaults
+	// to DefaultWebhookMemory.
+	Memory int
+	// SignatureHeader and SecretHeader name the headers checked for a
+	// signature or the shared secret. They default to the package constants
+	// of the same name.
+	SignatureHeader string
+	SecretHeader    string
+
+	secret []byte
+
+	mu    sync.Mutex
+	seen  map[string]bool
+	order []string
+}
+
+// NewWebhookHandler returns a handler that verifies notifications against
+// secret. An empty secret rejects every notification.
+func NewWebhookHandler(secret string) *WebhookHandler {
+	return &WebhookHandler{secret: []byte(secret), seen: make(map[string]bool)}
+}
+
+// SignWebhook returns the SignatureHeader value for body.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookPayload struct {
+	Payment     *Payment     `json:"payment"`
+	Transaction *Transaction `json:"transaction"`
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+	if err != nil || len(body) > maxWebhookBody {
+		http.Error(w, "unreadable body", http.StatusBadRequest)
+		return
+	}
+	if !h.verify(r, body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	var key string
+	var handle func() error
+	switch {
+	case payload.Payment != nil && payload.Payment.UUID != "":
+		p := *payload.Payment
+		key = strings.Join([]string{"payment", p.UUID, string(p.Status), p.UpdatedAt.String()}, " ")
+		if h.OnPayment != nil {
+			handle = func() error { return h.OnPayment(r.Context(), p) }
+		}
+	case payload.Transaction != nil && payload.Transaction.UUID != "":
+		t := *payload.Transaction
+		key = strings.Join([]string{"transaction", t.UUID, string(t.Status), t.UpdatedAt.String(), strconv.Itoa(t.Confirmations)}, " ")
+		if h.OnTransaction != nil {
+			handle = func() error { return h.OnTransaction(r.Context(), t) }
+		}
+	default:
+		http.Error(w, "no payment or transaction", http.StatusBadRequest)
+		return
+	}
+
+	if !h.claim(key) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if handle != nil {
+		if err := handle(); err != nil {
+			h.release(key)
+			http.Error(w, "notification not handled", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) verify(r *http.Request, body []byte) bool {
+	if len(h.secret) == 0 {
+		return false
+	}
+	signatureHeader, secretHeader := h.SignatureHeader, h.SecretHeader
+	if signatureHeader == "" {
+		signatureHeader = SignatureHeader
+	}
+	if secretHeader == "" {
+		secretHeader = SecretHeader
+	}
+	if sig := strings.ToLower(r.Header.Get(signatureHeader)); sig != "" {
+		want := SignWebhook(string(h.secret), body)
+		if !strings.HasPrefix(sig, "sha256=") {
+			want = strings.TrimPrefix(want, "sha256=")
+		}
+		return hmac.Equal([]byte(sig), []byte(want))
+	}
+	secret := r.Header.Get(secretHeader)
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
+	}
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), h.secret) == 1
+}
+
+// claim records key as handled, reporting false if it already was. Claiming
+// before the callback runs keeps concurrent redeliveries from running it
+// twice.
+func (h *WebhookHandler) claim(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seen[key] {
+		return false
+	}
+	h.seen[key] = true
+	h.order = append(h.order, key)
+	memory := h.Memory
+	if memory <= 0 {
+		memory = DefaultWebhookMemory
+	}
+	for len(h.order) > memory {
+		delete(h.seen, h.order[0])
+		h.order = h.order[1:]
+	}
+	return true
+}
+
+func (h *WebhookHandler) release(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.seen, key)
+	for i, k := range h.order {
+		if k == key {
+			h.order = append(h.order[:i], h.order[i+1:]...)
+			break
+		}
+	}
+}
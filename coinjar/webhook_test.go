@@ -0,0 +1,136 @@
+package coinjar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func deliver(h http.Handler, method, target string, header map[string]string, body string) int {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+const paymentNotification = `{"payment": {"uuid": "2a0e3bb4", "status": "COMPLETED", "amount": "1.25", "reference": "Order 42"}}`
+
+func TestWebhookSignedPayment(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	var payments []Payment
+	h.OnPayment = func(ctx context.Context, p Payment) error {
+		payments = append(payments, p)
+		return nil
+	}
+	signed := map[string]string{SignatureHeader: SignWebhook("whsecret", []byte(paymentNotification))}
+
+	assertEqual(t, deliver(h, "POST", "/notify", signed, paymentNotification), 200)
+	assertEqual(t, len(payments), 1)
+	assertEqual(t, payments[0].Status, PaymentCompleted)
+	assertEqual(t, payments[0].Amount.String(), "1.25")
+	assertEqual(t, payments[0].Reference, "Order 42")
+
+	// A redelivery is acknowledged without calling back again.
+	assertEqual(t, deliver(h, "POST", "/notify", signed, paymentNotification), 200)
+	assertEqual(t, len(payments), 1)
+
+	// The same payment with a new status is a new notification.
+	failed := strings.Replace(paymentNotification, "COMPLETED", "FAILED", 1)
+	unprefixed := strings.TrimPrefix(SignWebhook("whsecret", []byte(failed)), "sha256=")
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{SignatureHeader: strings.ToUpper(unprefixed)}, failed), 200)
+	assertEqual(t, len(payments), 2)
+}
+
+func TestWebhookRejects(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	h.OnPayment = func(ctx context.Context, p Payment) error {
+		t.Error("callback called")
+		return nil
+	}
+
+	assertEqual(t, deliver(h, "POST", "/notify", nil, paymentNotification), 401)
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{SignatureHeader: SignWebhook("other", []byte(paymentNotification))}, paymentNotification), 401)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=wrong", nil, paymentNotification), 401)
+	assertEqual(t, deliver(h, "GET", "/notify?secret=whsecret", nil, ""), 405)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, `{"payment": `), 400)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, `{"contact": {"uuid": "x"}}`), 400)
+	assertEqual(t, deliver(NewWebhookHandler(""), "POST", "/notify?secret=", nil, paymentNotification), 401)
+}
+
+func TestWebhookSharedSecretTransaction(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	var got Transaction
+	h.OnTransaction = func(ctx context.Context, tx Transaction) error {
+		got = tx
+		return nil
+	}
+	body := `{"transaction": {"uuid": "8e9e", "status": "RECEIVED", "amount": "0.5", "confirmations": "3"}}`
+
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{SecretHeader: "whsecret"}, body), 200)
+	assertEqual(t, got.Confirmations, 3)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, paymentNotification), 200)
+}
+
+func TestWebhookConfirmationUpdates(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	var confirmations []int
+	h.OnTransaction = func(ctx context.Context, tx Transaction) error {
+		confirmations = append(confirmations, tx.Confirmations)
+		return nil
+	}
+	notify := func(n string) int {
+		body := `{"transaction": {"uuid": "8e9e", "status": "RECEIVED", "confirmations": "` + n + `"}}`
+		return deliver(h, "POST", "/notify?secret=whsecret", nil, body)
+	}
+
+	assertEqual(t, notify("1"), 200)
+	assertEqual(t, notify("1"), 200)
+	assertEqual(t, notify("2"), 200)
+	assertEqual(t, fmt.Sprint(confirmations), "[1 2]")
+}
+
+func TestWebhookCustomHeaders(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	h.SignatureHeader = "X-Signature"
+	h.SecretHeader = "X-Secret"
+
+	signature := SignWebhook("whsecret", []byte(paymentNotification))
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{"X-Signature": signature}, paymentNotification), 200)
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{"X-Secret": "whsecret"}, paymentNotification), 200)
+	assertEqual(t, deliver(h, "POST", "/notify", map[string]string{SignatureHeader: signature}, paymentNotification), 401)
+}
+
+func TestWebhookCallbackErrorAllowsRedelivery(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	calls := 0
+	h.OnPayment = func(ctx context.Context, p Payment) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	}
+
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, paymentNotification), 500)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, paymentNotification), 200)
+	assertEqual(t, deliver(h, "POST", "/notify?secret=whsecret", nil, paymentNotification), 200)
+	assertEqual(t, calls, 2)
+}
+
+func TestWebhookMemory(t *testing.T) {
+	h := NewWebhookHandler("whsecret")
+	h.Memory = 2
+	assertEqual(t, h.claim("a"), true)
+	assertEqual(t, h.claim("b"), true)
+	assertEqual(t, h.claim("a"), false)
+	assertEqual(t, h.claim("c"), true)
+	assertEqual(t, h.claim("a"), true)
+	assertEqual(t, len(h.seen), 2)
+}